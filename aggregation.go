@@ -0,0 +1,324 @@
+package firevault
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+)
+
+// Aggregation represents a value (e.g. a count or a
+// sum) computed over the documents which match a
+// Query, by Aggregate.
+//
+// Aggregations are created using the Count, Sum and
+// Average functions.
+type Aggregation struct {
+	alias    string
+	operator aggregationOperator
+	path     string
+}
+
+// the computation performed by an Aggregation
+type aggregationOperator string
+
+const (
+	countOperator   aggregationOperator = "count"
+	sumOperator     aggregationOperator = "sum"
+	averageOperator aggregationOperator = "average"
+)
+
+// AggregationResult holds the results of an
+// Aggregate call, keyed by each Aggregation's alias.
+type AggregationResult map[string]AggregationValue
+
+// AggregationValue holds the result of a single
+// Aggregation.
+type AggregationValue struct {
+	value interface{} // int64, float64 or nil
+}
+
+// Count creates an Aggregation which counts the
+// documents matching a Query.
+func Count(alias string) Aggregation {
+	return Aggregation{alias, countOperator, ""}
+}
+
+// Sum creates an Aggregation which sums the numeric
+// values of a field, across the documents matching a
+// Query. Non-numeric values are ignored.
+//
+// The path argument can be a single field or a
+// dot-separated sequence of fields, using the names
+// of the model's firevault tags, and must exist on
+// the model.
+//
+// The result is an integer if all summed values are
+// integers (and the sum doesn't overflow), otherwise
+// it's a float.
+func Sum(alias string, path string) Aggregation {
+	return Aggregation{alias, sumOperator, path}
+}
+
+// Average creates an Aggregation which averages the
+// numeric values of a field, across the documents
+// matching a Query. Non-numeric values are ignored.
+//
+// The path argument is the same as that of Sum.
+//
+// The result is a float, or null if no numeric values
+// are found.
+func Average(alias string, path string) Aggregation {
+	return Aggregation{alias, averageOperator, path}
+}
+
+// Int returns the value as an integer. Floats are
+// truncated, while null values return 0.
+func (v AggregationValue) Int() int64 {
+	switch value := v.value.(type) {
+	case int64:
+		return value
+	case float64:
+		return int64(value)
+	}
+
+	return 0
+}
+
+// Float returns the value as a float. Null values
+// return 0.
+func (v AggregationValue) Float() float64 {
+	switch value := v.value.(type) {
+	case int64:
+		return float64(value)
+	case float64:
+		return value
+	}
+
+	return 0
+}
+
+// IsNull reports whether the value is null (e.g. the
+// average of no values).
+func (v AggregationValue) IsNull() bool {
+	return v.value == nil
+}
+
+// Interface returns the value as an int64, a float64
+// or nil.
+func (v AggregationValue) Interface() interface{} {
+	return v.value
+}
+
+// Compute aggregations (counts, sums and averages)
+// over the Firestore documents which match provided
+// Query, in a single request.
+//
+// For queries with an ID clause, only documents which
+// exist are aggregated.
+//
+// To use inside a transaction, pass a transaction
+// instance via Options.
+func (c *CollectionRef[T]) Aggregate(
+	ctx context.Context,
+	query Query,
+	aggregations []Aggregation,
+	opts ...Options,
+) (AggregationResult, error) {
+	if c == nil {
+		return nil, errors.New("firevault: nil CollectionRef")
+	}
+
+	if query.nearest != nil {
+		return nil, errors.New("firevault: aggregations cannot be used with FindNearest")
+	}
+
+	if len(aggregations) == 0 {
+		return nil, errors.New("firevault: at least one aggregation is required")
+	}
+
+	aliases := make(map[string]bool, len(aggregations))
+
+	for _, aggregation := range aggregations {
+		if aggregation.alias == "" {
+			return nil, errors.New("firevault: aggregation alias cannot be empty")
+		}
+
+		if aliases[aggregation.alias] {
+			return nil, errors.New("firevault: aggregation alias " + aggregation.alias + " is used more than once")
+		}
+
+		aliases[aggregation.alias] = true
+
+		if aggregation.operator != countOperator {
+			// catch typos, which would otherwise be ignored
+			_, _, err := c.connection.validator.resolveFieldPath(reflect.TypeFor[T](), aggregation.path, false)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	valOpts, _, _, _, _ := c.parseOptions(find, opts...)
+
+	if len(query.ids) > 0 {
+		// repeated IDs refer to the same document
+		ids := slices.Compact(slices.Sorted(slices.Values(query.ids)))
+
+		snapshots, err := c.fetchSnapshotsByID(ctx, valOpts.tx, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		return aggregateSnapshots(snapshots, aggregations), nil
+	}
+
+	builtQuery, err := c.buildQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregationQuery := builtQuery.NewAggregationQuery()
+
+	for _, aggregation := range aggregations {
+		path := firestore.FieldPath(strings.Split(aggregation.path, "."))
+
+		switch aggregation.operator {
+		case countOperator:
+			aggregationQuery = aggregationQuery.WithCount(aggregation.alias)
+		case sumOperator:
+			aggregationQuery = aggregationQuery.WithSumPath(path, aggregation.alias)
+		case averageOperator:
+			aggregationQuery = aggregationQuery.WithAvgPath(path, aggregation.alias)
+		default:
+			return nil, errors.New("firevault: invalid aggregation " + aggregation.alias)
+		}
+	}
+
+	if valOpts.tx != nil {
+		aggregationQuery = aggregationQuery.Transaction(valOpts.tx)
+	}
+
+	results, err := aggregationQuery.Get(ctx)
+	if err != nil {
+		return nil, wrapFirestoreError(err)
+	}
+
+	result := make(AggregationResult, len(aggregations))
+
+	for _, aggregation := range aggregations {
+		value, ok := results[aggregation.alias].(*firestorepb.Value)
+		if !ok {
+			return nil, errors.New("firevault: couldn't get aggregation " + aggregation.alias + " from results")
+		}
+
+		switch v := value.GetValueType().(type) {
+		case *firestorepb.Value_IntegerValue:
+			result[aggregation.alias] = AggregationValue{v.IntegerValue}
+		case *firestorepb.Value_DoubleValue:
+			result[aggregation.alias] = AggregationValue{v.DoubleValue}
+		default:
+			result[aggregation.alias] = AggregationValue{}
+		}
+	}
+
+	return result, nil
+}
+
+// Check whether any Firestore document matches
+// provided Query.
+//
+// Only the first matching document's ID is read, so
+// it's cheaper than counting all matches.
+//
+// To use inside a transaction, pass a transaction
+// instance via Options.
+func (c *CollectionRef[T]) Exists(ctx context.Context, query Query, opts ...Options) (bool, error) {
+	if c == nil {
+		return false, errors.New("firevault: nil CollectionRef")
+	}
+
+	valOpts, _, _, _, _ := c.parseOptions(find, opts...)
+
+	if len(query.ids) > 0 {
+		snapshots, err := c.fetchSnapshotsByID(ctx, valOpts.tx, query.ids)
+		return len(snapshots) > 0, err
+	}
+
+	if query.nearest != nil {
+		return false, errors.New("firevault: Exists cannot be used with FindNearest")
+	}
+
+	// only document IDs are needed
+	iter, _, err := c.queryIterator(ctx, valOpts.tx, query.Select().Limit(1))
+	if err != nil {
+		return false, err
+	}
+	defer iter.Stop()
+
+	_, err = iter.Next()
+	if err == Done {
+		return false, nil
+	}
+
+	return err == nil, wrapFirestoreError(err)
+}
+
+// compute aggregations over fetched documents (i.e. documents fetched by ID)
+func aggregateSnapshots(snapshots []*firestore.DocumentSnapshot, aggregations []Aggregation) AggregationResult {
+	result := make(AggregationResult, len(aggregations))
+
+	for _, aggregation := range aggregations {
+		if aggregation.operator == countOperator {
+			result[aggregation.alias] = AggregationValue{int64(len(snapshots))}
+			continue
+		}
+
+		var intSum int64
+		var floatSum float64
+		var count int
+		isInt := true
+
+		for _, docSnap := range snapshots {
+			value, err := docSnap.DataAtPath(firestore.FieldPath(strings.Split(aggregation.path, ".")))
+			if err != nil {
+				continue // missing fields are ignored
+			}
+
+			switch v := value.(type) {
+			case int64:
+				// integer sums which overflow become floats, like in Firestore
+				if isInt && ((v > 0 && intSum > (1<<63-1)-v) || (v < 0 && intSum < (-1<<63)-v)) {
+					isInt = false
+				}
+
+				intSum += v
+				floatSum += float64(v)
+			case float64:
+				isInt = false
+				floatSum += v
+			default:
+				continue // non-numeric values are ignored
+			}
+
+			count++
+		}
+
+		switch {
+		case aggregation.operator == averageOperator && count == 0:
+			result[aggregation.alias] = AggregationValue{}
+		case aggregation.operator == averageOperator:
+			result[aggregation.alias] = AggregationValue{floatSum / float64(count)}
+		case isInt:
+			result[aggregation.alias] = AggregationValue{intSum}
+		default:
+			result[aggregation.alias] = AggregationValue{floatSum}
+		}
+	}
+
+	return result
+}
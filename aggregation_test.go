@@ -0,0 +1,48 @@
+package firevault
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	type order struct {
+		Total int `firevault:"total"`
+	}
+
+	orders := &CollectionRef[order]{connection: &Connection{Validator: NewValidator()}, path: "orders"}
+
+	invalid := map[string][]Aggregation{
+		"no aggregations": nil,
+		"empty alias":     {Count("")},
+		"repeated alias":  {Count("n"), Sum("n", "total")},
+		"missing field":   {Average("avg", "totl")},
+	}
+
+	for name, aggregations := range invalid {
+		if _, err := orders.Aggregate(context.Background(), NewQuery(), aggregations); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+
+	if _, err := orders.Exists(context.Background(), NewQuery().FindNearest("v", []float32{1}, 1, DistanceMeasureCosine)); err == nil {
+		t.Errorf("Expected error for Exists with FindNearest")
+	}
+
+	values := []struct {
+		value  AggregationValue
+		int    int64
+		float  float64
+		isNull bool
+	}{
+		{AggregationValue{int64(3)}, 3, 3, false},
+		{AggregationValue{2.5}, 2, 2.5, false},
+		{AggregationValue{}, 0, 0, true},
+	}
+
+	for _, tc := range values {
+		if tc.value.Int() != tc.int || tc.value.Float() != tc.float || tc.value.IsNull() != tc.isNull {
+			t.Errorf("Unexpected conversions of %v", tc.value.Interface())
+		}
+	}
+}
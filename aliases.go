@@ -1,6 +1,9 @@
 package firevault
 
-import "cloud.google.com/go/firestore"
+import (
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
 
 // Transaction is an alias of Firestore's
 // Transaction.
@@ -19,6 +22,22 @@ const Asc Direction = firestore.Asc
 // Desc sorts results from largest to smallest.
 const Desc Direction = firestore.Desc
 
+// DistanceMeasure is the distance measure used
+// when comparing vectors during a FindNearest search.
+type DistanceMeasure = firestore.DistanceMeasure
+
+// DistanceMeasureEuclidean measures the Euclidean
+// distance between vectors.
+const DistanceMeasureEuclidean DistanceMeasure = firestore.DistanceMeasureEuclidean
+
+// DistanceMeasureCosine compares vectors based on
+// the angle between them.
+const DistanceMeasureCosine DistanceMeasure = firestore.DistanceMeasureCosine
+
+// DistanceMeasureDotProduct is similar to cosine,
+// but is affected by the magnitude of vectors.
+const DistanceMeasureDotProduct DistanceMeasure = firestore.DistanceMeasureDotProduct
+
 // ServerTimestamp is used as a value in a call to
 // Update to indicate that the key's value should be
 // set to the time at which the server processed the
@@ -83,3 +102,7 @@ func ArrayUnion(elements ...interface{}) interface{} {
 func ArrayRemove(elements ...interface{}) interface{} {
 	return firestore.ArrayRemove(elements...)
 }
+
+// Done is returned by a DocumentIterator's Next
+// method when there are no more documents.
+var Done = iterator.Done
@@ -16,6 +16,10 @@ var (
 		"omitempty_create":   {},
 		"omitempty_update":   {},
 		"omitempty_validate": {},
+		"immutable":          {},
+		"createonly":         {},
+		"transitions":        {},
+		"inline":             {},
 	}
 
 	builtInValidators = map[string]ValidationFunc{
@@ -26,6 +30,12 @@ var (
 		"email":             validateEmail,
 		"max":               validateMax,
 		"min":               validateMin,
+		"latitude":          validateLatitude,
+		"longitude":         validateLongitude,
+		"geopoint":          validateGeopoint,
+		"dimension":         validateDimension,
+		"max_bytes":         validateMaxBytes,
+		"collection":        validateCollection,
 	}
 
 	builtInTransformators = map[string]TransformationFunc{
@@ -171,6 +181,89 @@ func validateMin(fs FieldScope) (bool, error) {
 	return false, errors.New("firevault: invalid field type - " + fs.Path())
 }
 
+// validates if field's numeric value is a valid latitude
+func validateLatitude(fs FieldScope) (bool, error) {
+	f, ok := asNumber(fs.Value())
+	if !ok {
+		return false, errors.New("firevault: invalid field type - " + fs.Path())
+	}
+
+	return f >= -90 && f <= 90, nil
+}
+
+// validates if field's numeric value is a valid longitude
+func validateLongitude(fs FieldScope) (bool, error) {
+	f, ok := asNumber(fs.Value())
+	if !ok {
+		return false, errors.New("firevault: invalid field type - " + fs.Path())
+	}
+
+	return f >= -180 && f <= 180, nil
+}
+
+// validates if field's geopoint has a valid latitude and longitude
+func validateGeopoint(fs FieldScope) (bool, error) {
+	if fs.Type() != latLngType {
+		return false, errors.New("firevault: invalid field type - " + fs.Path())
+	}
+
+	point := asLatLng(fs.Value())
+
+	return point.GetLatitude() >= -90 && point.GetLatitude() <= 90 &&
+		point.GetLongitude() >= -180 && point.GetLongitude() <= 180, nil
+}
+
+// validates if field's vector (or slice) length is equal to param's value
+func validateDimension(fs FieldScope) (bool, error) {
+	if fs.Param() == "" {
+		return false, errors.New("firevault: provide a dimension param - " + fs.Path())
+	}
+
+	if fs.Kind() != reflect.Slice && fs.Kind() != reflect.Array {
+		return false, errors.New("firevault: invalid field type - " + fs.Path())
+	}
+
+	i, err := asInt(fs.Param())
+	if err != nil {
+		return false, err
+	}
+
+	return fs.Value().Len() == int(i), nil
+}
+
+// validates if field's byte size is less than or equal to param's value
+func validateMaxBytes(fs FieldScope) (bool, error) {
+	if fs.Param() == "" {
+		return false, errors.New("firevault: provide a max_bytes param - " + fs.Path())
+	}
+
+	if fs.Kind() != reflect.String && (fs.Kind() != reflect.Slice || !isBytes(fs.Type())) {
+		return false, errors.New("firevault: invalid field type - " + fs.Path())
+	}
+
+	i, err := asInt(fs.Param())
+	if err != nil {
+		return false, err
+	}
+
+	return fs.Value().Len() <= int(i), nil
+}
+
+// validates if field's document reference belongs to param's collection
+func validateCollection(fs FieldScope) (bool, error) {
+	if fs.Param() == "" {
+		return false, errors.New("firevault: provide a collection param - " + fs.Path())
+	}
+
+	if fs.Type() != docRefType {
+		return false, errors.New("firevault: invalid field type - " + fs.Path())
+	}
+
+	ref := asDocRef(fs.Value())
+
+	return ref.Parent != nil && ref.Parent.ID == fs.Param(), nil
+}
+
 // transforms a field of string type to upper case
 func transformUppercase(fs FieldScope) (interface{}, error) {
 	if fs.Kind() != reflect.String {
@@ -0,0 +1,746 @@
+// Command firevault-gen generates validate and marshal functions for
+// structs with firevault tags, which access fields directly instead of
+// walking the struct via reflection.
+//
+// The generated functions produce the same map the reflection-based
+// validator produces, and are used automatically by a Validator (or
+// the Connection embedding it), once registered via the generated
+// RegisterFirevaultValidators function. Custom validations and
+// transformations are still called by name, through the Validator's
+// registry. Fields with rules are still reflected when their rules
+// are applied, as rules receive the field's value through a FieldScope.
+//
+// Usage:
+//
+//	//go:generate go run github.com/bobch27/firevault_go/cmd/firevault-gen -type=User,Order
+//
+// Flags:
+//
+//	-type    comma-separated list of struct types (default: all structs with firevault tags)
+//	-output  output file name (default: firevault_gen.go)
+//	-dir     package directory (default: current directory)
+//
+// Structs using field types which can't be resolved from the package's
+// source (e.g. types from other packages, other than time.Time) are
+// skipped, and continue to be validated using reflection.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const firevaultImport = "github.com/bobch27/firevault_go"
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of struct types")
+	output := flag.String("output", "firevault_gen.go", "output file name")
+	dir := flag.String("dir", ".", "package directory")
+	flag.Parse()
+
+	var types []string
+	if *typeNames != "" {
+		types = strings.Split(*typeNames, ",")
+	}
+
+	src, err := generate(*dir, *output, types)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "firevault-gen:", err)
+		os.Exit(1)
+	}
+
+	err = os.WriteFile(filepath.Join(*dir, *output), src, 0o644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "firevault-gen:", err)
+		os.Exit(1)
+	}
+}
+
+// kind of a resolved field type
+type typeKind int
+
+const (
+	kindBasic typeKind = iota
+	kindTime
+	kindStruct
+	kindSlice
+	kindMap
+	kindAny
+)
+
+// resolved field type
+type typeInfo struct {
+	kind    typeKind
+	expr    string // dereferenced Go type expression
+	zero    string // zero value of basic types
+	pointer bool
+	elem    *typeInfo
+}
+
+// parsed struct field
+type fieldInfo struct {
+	goName   string
+	name     string
+	jsonName string
+	tag      string
+	dive     bool
+	typ      *typeInfo
+}
+
+// holds package data during generation
+type generator struct {
+	pkg       string
+	structs   map[string]*ast.StructType
+	named     map[string]ast.Expr
+	custom    map[string]bool // types implementing Valuer or Scanner
+	fields    map[string][]*fieldInfo
+	resolving map[string]bool
+	failed    map[string]error
+	order     []string
+	buf       bytes.Buffer
+}
+
+// basic types and their zero values
+var basicZero = map[string]string{
+	"string": `""`, "bool": "false",
+	"int": "0", "int8": "0", "int16": "0", "int32": "0", "int64": "0",
+	"uint": "0", "uint8": "0", "uint16": "0", "uint32": "0", "uint64": "0",
+	"float32": "0", "float64": "0", "byte": "0", "rune": "0",
+}
+
+// parse package in dir and generate source for the provided types
+func generate(dir string, output string, types []string) ([]byte, error) {
+	g := &generator{
+		structs:   make(map[string]*ast.StructType),
+		named:     make(map[string]ast.Expr),
+		custom:    make(map[string]bool),
+		fields:    make(map[string][]*fieldInfo),
+		resolving: make(map[string]bool),
+		failed:    make(map[string]error),
+	}
+
+	err := g.parseDir(dir, output)
+	if err != nil {
+		return nil, err
+	}
+
+	// default to all structs with firevault tags
+	if len(types) == 0 {
+		for name, st := range g.structs {
+			if g.hasFirevaultTags(st) {
+				types = append(types, name)
+			}
+		}
+
+		slices.Sort(types)
+	}
+
+	for _, name := range types {
+		name = strings.TrimSpace(name)
+		if _, ok := g.structs[name]; !ok {
+			return nil, fmt.Errorf("struct type %s not found", name)
+		}
+
+		if err := g.resolveStruct(name); err != nil {
+			fmt.Fprintf(os.Stderr, "firevault-gen: skipping %s: %v\n", name, err)
+		}
+	}
+
+	if len(g.order) == 0 {
+		return nil, errors.New("no struct types to generate")
+	}
+
+	g.writeFile()
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated source: %w", err)
+	}
+
+	return src, nil
+}
+
+// parse all non-test Go files in dir (except the output file)
+func (g *generator) parseDir(dir string, output string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+
+	for _, path := range paths {
+		base := filepath.Base(path)
+		if base == output || strings.HasSuffix(base, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+
+		g.pkg = file.Name.Name
+
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok {
+				g.addCustomType(fn)
+				continue
+			}
+
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+
+				// generic and alias types aren't supported
+				if ts.TypeParams != nil || ts.Assign.IsValid() {
+					continue
+				}
+
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					g.structs[ts.Name.Name] = st
+				} else {
+					g.named[ts.Name.Name] = ts.Type
+				}
+			}
+		}
+	}
+
+	if g.pkg == "" {
+		return fmt.Errorf("no Go files found in %s", dir)
+	}
+
+	return nil
+}
+
+// record receiver type of Valuer and Scanner methods
+func (g *generator) addCustomType(fn *ast.FuncDecl) {
+	if fn.Recv == nil || len(fn.Recv.List) != 1 ||
+		(fn.Name.Name != "FirestoreValue" && fn.Name.Name != "ScanFirestore") {
+		return
+	}
+
+	recv := fn.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+
+	if ident, ok := recv.(*ast.Ident); ok {
+		g.custom[ident.Name] = true
+	}
+}
+
+// check if struct has at least one firevault tag
+func (g *generator) hasFirevaultTags(st *ast.StructType) bool {
+	for _, field := range st.Fields.List {
+		if g.firevaultTag(field) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// get field's tag by key
+func (g *generator) tag(field *ast.Field, key string) string {
+	if field.Tag == nil {
+		return ""
+	}
+
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+
+	return reflect.StructTag(tag).Get(key)
+}
+
+// get field's firevault tag (empty if ignored)
+func (g *generator) firevaultTag(field *ast.Field) string {
+	tag := g.tag(field, "firevault")
+	if tag == "-" {
+		return ""
+	}
+
+	return tag
+}
+
+// resolve struct fields (and nested structs), in generation order
+func (g *generator) resolveStruct(name string) error {
+	if err, ok := g.failed[name]; ok {
+		return err
+	}
+
+	// already resolved, or currently resolving (self-referencing types)
+	if _, ok := g.fields[name]; ok || g.resolving[name] {
+		return nil
+	}
+
+	g.resolving[name] = true
+	defer func() { g.resolving[name] = false }()
+
+	fields, err := g.resolveFields(g.structs[name])
+	if err != nil {
+		g.failed[name] = err
+		return err
+	}
+
+	g.fields[name] = fields
+	g.order = append(g.order, name)
+	return nil
+}
+
+// resolve each tagged field of a struct
+func (g *generator) resolveFields(st *ast.StructType) ([]*fieldInfo, error) {
+	var fields []*fieldInfo
+
+	for _, field := range st.Fields.List {
+		tag := g.firevaultTag(field)
+
+		// inlined (and embedded) structs are flattened, which isn't supported
+		if g.isInline(field, tag) {
+			return nil, errors.New("inline and embedded struct fields are not supported")
+		}
+
+		if tag == "" {
+			continue
+		}
+
+		typ, err := g.resolveType(field.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(field.Names))
+		for _, ident := range field.Names {
+			names = append(names, ident.Name)
+		}
+
+		// embedded fields use their type name
+		if len(names) == 0 {
+			names = append(names, g.embeddedName(field.Type))
+		}
+
+		rules := strings.Split(tag, ",")
+		for i, rule := range rules {
+			rules[i] = strings.TrimSpace(rule)
+		}
+
+		for _, goName := range names {
+			fi := &fieldInfo{
+				goName:   goName,
+				name:     rules[0],
+				jsonName: goName,
+				tag:      tag,
+				dive:     slices.Contains(rules, "dive"),
+				typ:      typ,
+			}
+
+			if fi.name == "" {
+				fi.name = goName
+			}
+
+			jsonName, _, _ := strings.Cut(g.tag(field, "json"), ",")
+			if jsonName != "" && jsonName != "-" {
+				fi.jsonName = jsonName
+			}
+
+			fields = append(fields, fi)
+		}
+	}
+
+	// nested structs must be generated too
+	for _, fi := range fields {
+		for typ := fi.typ; typ != nil; typ = typ.elem {
+			if typ.kind != kindStruct {
+				continue
+			}
+
+			if err := g.resolveStruct(typ.expr); err != nil {
+				return nil, fmt.Errorf("field %s: %w", fi.goName, err)
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+// check if field's nested fields are promoted into its parent
+func (g *generator) isInline(field *ast.Field, tag string) bool {
+	if tag == "" {
+		return false
+	}
+
+	rules := strings.Split(tag, ",")
+	for _, rule := range rules[1:] {
+		if strings.TrimSpace(rule) == "inline" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// get name of an embedded field
+func (g *generator) embeddedName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return g.embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.Ident:
+		return e.Name
+	default:
+		return ""
+	}
+}
+
+// resolve a field type expression
+func (g *generator) resolveType(expr ast.Expr) (*typeInfo, error) {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		typ, err := g.resolveType(e.X)
+		if err != nil {
+			return nil, err
+		}
+
+		if typ.pointer {
+			return nil, errors.New("pointers to pointers are not supported")
+		}
+
+		typ.pointer = true
+		return typ, nil
+	case *ast.Ident:
+		if zero, ok := basicZero[e.Name]; ok {
+			return &typeInfo{kind: kindBasic, expr: e.Name, zero: zero}, nil
+		}
+
+		// custom types are marshalled at runtime, using reflection
+		if g.custom[e.Name] {
+			return nil, fmt.Errorf("custom type %s is not supported", e.Name)
+		}
+
+		if e.Name == "any" {
+			return &typeInfo{kind: kindAny, expr: e.Name}, nil
+		}
+
+		if _, ok := g.structs[e.Name]; ok {
+			return &typeInfo{kind: kindStruct, expr: e.Name}, nil
+		}
+
+		// named interfaces (holding registered types)
+		if underlying, ok := g.named[e.Name].(*ast.InterfaceType); ok && underlying != nil {
+			return &typeInfo{kind: kindAny, expr: e.Name}, nil
+		}
+
+		// named basic, slice and map types
+		if underlying, ok := g.named[e.Name]; ok {
+			typ, err := g.resolveType(underlying)
+			if err != nil {
+				return nil, err
+			}
+
+			if typ.pointer || typ.kind == kindStruct || typ.kind == kindTime {
+				return nil, fmt.Errorf("named type %s is not supported", e.Name)
+			}
+
+			typ.expr = e.Name
+			return typ, nil
+		}
+
+		return nil, fmt.Errorf("type %s is not supported", e.Name)
+	case *ast.SelectorExpr:
+		if pkg, ok := e.X.(*ast.Ident); ok && pkg.Name == "time" && e.Sel.Name == "Time" {
+			return &typeInfo{kind: kindTime, expr: "time.Time"}, nil
+		}
+
+		return nil, fmt.Errorf("type %s is not supported", g.exprString(e))
+	case *ast.ArrayType:
+		if e.Len != nil {
+			return nil, errors.New("arrays are not supported")
+		}
+
+		elem, err := g.resolveType(e.Elt)
+		if err != nil {
+			return nil, err
+		}
+
+		return &typeInfo{kind: kindSlice, expr: "[]" + g.typeString(elem), elem: elem}, nil
+	case *ast.MapType:
+		if key, ok := e.Key.(*ast.Ident); !ok || key.Name != "string" {
+			return nil, errors.New("only maps with string keys are supported")
+		}
+
+		elem, err := g.resolveType(e.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return &typeInfo{kind: kindMap, expr: "map[string]" + g.typeString(elem), elem: elem}, nil
+	case *ast.InterfaceType:
+		if len(e.Methods.List) > 0 {
+			return nil, errors.New("non-empty interfaces are not supported")
+		}
+
+		return &typeInfo{kind: kindAny, expr: "interface{}"}, nil
+	default:
+		return nil, fmt.Errorf("type %s is not supported", g.exprString(expr))
+	}
+}
+
+// get Go type expression of a resolved type (including pointer)
+func (g *generator) typeString(typ *typeInfo) string {
+	if typ.pointer {
+		return "*" + typ.expr
+	}
+
+	return typ.expr
+}
+
+// get source of an expression, used in error messages
+func (g *generator) exprString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	_ = format.Node(&buf, token.NewFileSet(), expr)
+	return buf.String()
+}
+
+// write a line to the output
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+// write the generated file
+func (g *generator) writeFile() {
+	var body bytes.Buffer
+	g.buf, body = body, g.buf
+
+	for _, name := range g.order {
+		g.writeStruct(name)
+	}
+
+	g.writeRegister()
+
+	g.buf, body = body, g.buf
+
+	g.printf("// Code generated by firevault-gen. DO NOT EDIT.")
+	g.printf("")
+	g.printf("package %s", g.pkg)
+	g.printf("")
+	g.printf("import (")
+
+	if bytes.Contains(body.Bytes(), []byte("time.Time")) {
+		g.printf("%q", "time")
+		g.printf("")
+	}
+
+	g.printf("firevault %q", firevaultImport)
+	g.printf(")")
+	g.printf("")
+	g.buf.Write(body.Bytes())
+}
+
+// write the registration function
+func (g *generator) writeRegister() {
+	g.printf("// RegisterFirevaultValidators registers the generated validate and")
+	g.printf("// marshal functions with the provided Validator.")
+	g.printf("func RegisterFirevaultValidators(validator *firevault.Validator) error {")
+
+	for _, name := range g.order {
+		g.printf("if err := firevault.RegisterGenerated(validator, firevaultValidate%s); err != nil {", name)
+		g.printf("return err")
+		g.printf("}")
+		g.printf("")
+	}
+
+	g.printf("return nil")
+	g.printf("}")
+}
+
+// write the field table and function of a struct
+func (g *generator) writeStruct(name string) {
+	fields := g.fields[name]
+	table := "firevaultFields" + name
+
+	g.printf("var %s = [...]firevault.GeneratedField{", table)
+	for _, fi := range fields {
+		g.printf("{Name: %q, StructName: %q, JSONName: %q, Tag: %q, Pointer: %t},",
+			fi.name, fi.goName, fi.jsonName, fi.tag, fi.typ.pointer)
+	}
+	g.printf("}")
+	g.printf("")
+
+	g.printf("func firevaultValidate%s(s *firevault.GeneratedScope, data *%s) (map[string]interface{}, error) {", name, name)
+	g.printf("s.Enter(data)")
+	g.printf("m := make(map[string]interface{}, %d)", len(fields))
+
+	for i, fi := range fields {
+		g.printf("")
+		g.writeField(fi, fmt.Sprintf("&%s[%d]", table, i))
+	}
+
+	g.printf("")
+	g.printf("return m, nil")
+	g.printf("}")
+	g.printf("")
+}
+
+// write the processing of a single field
+func (g *generator) writeField(fi *fieldInfo, field string) {
+	typ := fi.typ
+	value := "data." + fi.goName
+
+	g.printf("// %s", fi.goName)
+	g.printf("if keep, err := s.Keep(%s, m, %s, &%s); err != nil {", field, g.hasValue(typ, value), value)
+	g.printf("return nil, err")
+	g.printf("} else if keep {")
+
+	if typ.pointer {
+		g.printf("var x %s", typ.expr)
+		g.printf("ok := %s != nil", value)
+		g.printf("if ok {")
+		g.printf("x = *%s", value)
+		g.printf("}")
+	} else {
+		g.printf("x, ok := %s, true", value)
+	}
+
+	g.printf("x, ok, err = firevault.ApplyGeneratedRules(s, %s, &%s, x, ok)", field, value)
+	g.printf("if err != nil {")
+	g.printf("return nil, err")
+	g.printf("}")
+	g.printf("")
+	g.printf("if ok {")
+
+	switch {
+	case typ.kind == kindStruct:
+		// use original struct, so it can be modified by nested transformations
+		ptr := value
+		if !typ.pointer {
+			ptr = "&" + value
+		}
+
+		g.printf("sub, err := firevaultValidate%s(s.Child(%s), %s)", typ.expr, field, ptr)
+		g.printf("if err != nil {")
+		g.printf("return nil, err")
+		g.printf("}")
+		g.printf("")
+		g.printf("m[%q] = sub", fi.name)
+	case fi.dive && typ.kind == kindSlice:
+		g.printf("items := make([]interface{}, len(x))")
+		if typ.elem.kind == kindStruct || typ.elem.kind == kindAny {
+			g.printf("scope := s.Child(%s)", field)
+		}
+
+		g.printf("")
+		g.printf("for i := range x {")
+		g.writeElem(typ.elem, "x[i]", "items[i]", "scope.Index(i)")
+		g.printf("}")
+		g.printf("")
+		g.printf("m[%q] = items", fi.name)
+	case fi.dive && typ.kind == kindMap:
+		g.printf("items := make(map[string]interface{}, len(x))")
+		if typ.elem.kind == kindStruct || typ.elem.kind == kindAny {
+			g.printf("scope := s.Child(%s)", field)
+		}
+
+		g.printf("")
+		g.printf("for k, e := range x {")
+		if typ.elem.pointer {
+			g.printf("items[k] = nil")
+		}
+
+		g.writeElem(typ.elem, "e", "items[k]", "scope.Key(k)")
+		g.printf("}")
+		g.printf("")
+		g.printf("m[%q] = items", fi.name)
+	case typ.kind == kindAny:
+		// validate registered types held by interface
+		g.printf("sub, err := s.Child(%s).Value(x)", field)
+		g.printf("if err != nil {")
+		g.printf("return nil, err")
+		g.printf("}")
+		g.printf("")
+		g.printf("m[%q] = sub", fi.name)
+	default:
+		g.printf("m[%q] = x", fi.name)
+	}
+
+	g.printf("}")
+	g.printf("}")
+}
+
+// write the processing of a dived slice/map element
+func (g *generator) writeElem(typ *typeInfo, elem string, target string, scope string) {
+	if typ.pointer {
+		g.printf("if %s != nil {", elem)
+	}
+
+	switch {
+	case typ.kind == kindStruct:
+		ptr := elem
+		if !typ.pointer {
+			ptr = "&" + elem
+		}
+
+		g.printf("sub, err := firevaultValidate%s(%s, %s)", typ.expr, scope, ptr)
+		g.printf("if err != nil {")
+		g.printf("return nil, err")
+		g.printf("}")
+		g.printf("")
+		g.printf("%s = sub", target)
+	case typ.kind == kindAny:
+		g.printf("sub, err := %s.Value(%s)", scope, elem)
+		g.printf("if err != nil {")
+		g.printf("return nil, err")
+		g.printf("}")
+		g.printf("")
+		g.printf("%s = sub", target)
+	case typ.pointer:
+		g.printf("%s = *%s", target, elem)
+	default:
+		g.printf("%s = %s", target, elem)
+	}
+
+	if typ.pointer {
+		g.printf("}")
+	}
+}
+
+// get expression reporting whether a field has a (non-zero) value
+func (g *generator) hasValue(typ *typeInfo, value string) string {
+	if typ.pointer {
+		return value + " != nil"
+	}
+
+	switch typ.kind {
+	case kindSlice, kindMap, kindAny:
+		return value + " != nil"
+	case kindTime:
+		return value + " != (time.Time{})"
+	case kindStruct:
+		return "s.HasValue(&" + value + ")"
+	default:
+		if typ.zero == "false" {
+			return value
+		}
+
+		return value + " != " + typ.zero
+	}
+}
@@ -3,11 +3,13 @@ package firevault
 import (
 	"context"
 	"errors"
-	"sync"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
-	"cloud.google.com/go/firestore/apiv1/firestorepb"
 	"google.golang.org/api/iterator"
 )
 
@@ -24,6 +26,7 @@ type CollectionRef[T interface{}] struct {
 	connection *Connection
 	ref        *firestore.CollectionRef
 	path       string
+	group      *firestore.CollectionGroupRef // only set for collection groups
 }
 
 // Document holds the ID and data related to a fetched
@@ -32,6 +35,40 @@ type Document[T interface{}] struct {
 	ID       string
 	Data     T
 	Metadata metadata
+	// The document's path, relative to the database
+	// (e.g. "posts/6QVHL46WCE680ZG2Xn3X/comments/c1").
+	Path string
+}
+
+// ParentPath returns the path of the document's parent
+// document (e.g. "posts/6QVHL46WCE680ZG2Xn3X", for a
+// document of its "comments" subcollection).
+//
+// Returns an empty string for top-level documents.
+func (d Document[T]) ParentPath() string {
+	segments := strings.Split(d.Path, "/")
+	if len(segments) < 4 {
+		return ""
+	}
+
+	return strings.Join(segments[:len(segments)-2], "/")
+}
+
+// ParentIDs returns the IDs of the document's ancestor
+// documents, starting from the root (e.g. the ID of the
+// post, for a document of its "comments" subcollection).
+//
+// Returns an empty slice for top-level documents.
+func (d Document[T]) ParentIDs() []string {
+	segments := strings.Split(d.Path, "/")
+	ids := []string{}
+
+	// document IDs follow each collection ID, the last being the document's own
+	for i := 1; i < len(segments)-1; i += 2 {
+		ids = append(ids, segments[i])
+	}
+
+	return ids
 }
 
 // read-only document metadata
@@ -46,6 +83,9 @@ type metadata struct {
 	UpdateTime time.Time
 	// Read-only. The time at which the document was read.
 	ReadTime time.Time
+	// Read-only. The document's vector distance from the
+	// query vector. Only set by FindNearest searches.
+	Distance float64
 }
 
 // Create a new CollectionRef instance.
@@ -75,7 +115,7 @@ func Collection[T interface{}](connection *Connection, path string) *CollectionR
 		return nil
 	}
 
-	return &CollectionRef[T]{connection, collectionRef, path}
+	return &CollectionRef[T]{connection, collectionRef, path, nil}
 }
 
 // Validate and transform provided data.
@@ -112,21 +152,24 @@ func (c *CollectionRef[T]) Create(ctx context.Context, data *T, opts ...Options)
 
 	valOpts, id, _, _, _ := c.parseOptions(create, opts...)
 
-	dataMap, err := c.connection.validator.validate(ctx, data, valOpts)
-	if err != nil {
-		return "", err
-	}
-
 	if id == "" {
 		docRef := c.ref.NewDoc() // generates doc ref with random id (used in c.ref.Add)
 		id = docRef.ID
 	}
 
+	// make id available to validators
+	valOpts.docIDs = []string{id}
+
+	dataMap, err := c.connection.validator.validate(ctx, data, valOpts)
+	if err != nil {
+		return "", err
+	}
+
 	// perform transaction if provided opt
 	if valOpts.tx != nil {
 		query := Query{ids: []string{id}} // needed to run transac operation
-		err = c.transacOperation(valOpts.tx, query, func(tx *firestore.Transaction, docID string) error {
-			return tx.Create(c.ref.Doc(docID), dataMap)
+		err = c.transacOperation(valOpts.tx, query, func(tx *firestore.Transaction, ref *firestore.DocumentRef, _ string) error {
+			return tx.Create(ref, dataMap)
 		})
 		if err != nil {
 			return "", err
@@ -137,7 +180,7 @@ func (c *CollectionRef[T]) Create(ctx context.Context, data *T, opts ...Options)
 
 	_, err = c.ref.Doc(id).Create(ctx, dataMap)
 	if err != nil {
-		return "", err
+		return "", &DocError{id, wrapFirestoreError(err)}
 	}
 
 	return id, nil
@@ -168,6 +211,14 @@ func (c *CollectionRef[T]) Create(ctx context.Context, data *T, opts ...Options)
 // The operation is not atomic, unless used inside a
 // transaction via Options.
 //
+// To validate against the currently stored documents,
+// use the LoadPrevious option. Data is then validated
+// once per matching document. This is done
+// automatically for structs using the "transitions"
+// rule, which also requires a transaction. Outside a
+// transaction, a document which changes after being
+// read is not updated, returning ErrPreconditionFailed.
+//
 // Note: In a transaction with no ID clause, document
 // IDs are read first, which prevents any prior writes
 // or subsequent reads in the same transaction. To work
@@ -180,6 +231,20 @@ func (c *CollectionRef[T]) Update(ctx context.Context, query Query, data *T, opt
 
 	valOpts, _, precond, merge, mergeFields := c.parseOptions(update, opts...)
 
+	// transitions need the stored values to be read and validated atomically
+	hasTransitions := c.connection.validator.usesRule(reflect.TypeOf(data), "transitions")
+	if hasTransitions && valOpts.tx == nil {
+		return errors.New("firevault: transitions rule requires a transaction")
+	}
+
+	// validate against each stored document, if requested
+	if valOpts.options.loadPrevious || hasTransitions {
+		return c.updateWithPrevious(ctx, query, data, valOpts, precond, merge, mergeFields)
+	}
+
+	// make ids available to validators
+	valOpts.docIDs = query.ids
+
 	dataMap, err := c.connection.validator.validate(ctx, data, valOpts)
 	if err != nil {
 		return err
@@ -189,23 +254,21 @@ func (c *CollectionRef[T]) Update(ctx context.Context, query Query, data *T, opt
 
 	// perform transaction if provided opt
 	if valOpts.tx != nil {
-		return c.transacOperation(valOpts.tx, query, func(tx *firestore.Transaction, docID string) error {
+		return c.transacOperation(valOpts.tx, query, func(tx *firestore.Transaction, ref *firestore.DocumentRef, _ string) error {
 			if precond != nil {
-				return tx.Update(c.ref.Doc(docID), updates, precond)
+				return tx.Update(ref, updates, precond)
 			}
 
-			return tx.Update(c.ref.Doc(docID), updates)
+			return tx.Update(ref, updates)
 		})
 	}
 
-	return c.bulkOperation(ctx, query, func(bw *firestore.BulkWriter, docID string) error {
+	return c.bulkOperation(ctx, query, func(bw *firestore.BulkWriter, ref *firestore.DocumentRef, _ string) (*firestore.BulkWriterJob, error) {
 		if precond != nil {
-			_, err := bw.Update(c.ref.Doc(docID), updates, precond)
-			return err
+			return bw.Update(ref, updates, precond)
 		}
 
-		_, err := bw.Update(c.ref.Doc(docID), updates)
-		return err
+		return bw.Update(ref, updates)
 	})
 }
 
@@ -230,6 +293,10 @@ func (c *CollectionRef[T]) Update(ctx context.Context, query Query, data *T, opt
 // The operation is not atomic, unless used inside a
 // transaction via Options.
 //
+// To also delete the documents of registered
+// subcollections, use the Cascade option. Outside a
+// transaction, cascades aren't atomic either.
+//
 // Note: In a transaction with no ID clause, document
 // IDs are read first, which prevents any prior writes
 // or subsequent reads in the same transaction. To work
@@ -242,25 +309,27 @@ func (c *CollectionRef[T]) Delete(ctx context.Context, query Query, opts ...Opti
 
 	valOpts, _, precond, _, _ := c.parseOptions(delete, opts...)
 
+	if valOpts.options.cascade {
+		return c.cascadeDelete(ctx, valOpts.tx, query, precond)
+	}
+
 	// perform transaction if provided opt
 	if valOpts.tx != nil {
-		return c.transacOperation(valOpts.tx, query, func(tx *firestore.Transaction, docID string) error {
+		return c.transacOperation(valOpts.tx, query, func(tx *firestore.Transaction, ref *firestore.DocumentRef, _ string) error {
 			if precond != nil {
-				return tx.Delete(c.ref.Doc(docID), precond)
+				return tx.Delete(ref, precond)
 			}
 
-			return tx.Delete(c.ref.Doc(docID))
+			return tx.Delete(ref)
 		})
 	}
 
-	return c.bulkOperation(ctx, query, func(bw *firestore.BulkWriter, docID string) error {
+	return c.bulkOperation(ctx, query, func(bw *firestore.BulkWriter, ref *firestore.DocumentRef, _ string) (*firestore.BulkWriterJob, error) {
 		if precond != nil {
-			_, err := bw.Delete(c.ref.Doc(docID), precond)
-			return err
+			return bw.Delete(ref, precond)
 		}
 
-		_, err := bw.Delete(c.ref.Doc(docID))
-		return err
+		return bw.Delete(ref)
 	})
 }
 
@@ -287,8 +356,9 @@ func (c *CollectionRef[T]) Find(ctx context.Context, query Query, opts ...Option
 // matches provided Query.
 //
 // Returns an empty Document[T] (empty ID
-// string and zero-value T Data), and no error
-// if no documents are found.
+// string and zero-value T Data), and
+// ErrNotFound if no documents are found (wrapped
+// in a DocError, if the Query has an ID clause).
 //
 // To use inside a transaction, pass a transaction
 // instance via Options.
@@ -306,19 +376,26 @@ func (c *CollectionRef[T]) FindOne(ctx context.Context, query Query, opts ...Opt
 		}
 
 		if len(docs) == 0 {
-			return Document[T]{}, nil
+			return Document[T]{}, &DocError{query.ids[0], ErrNotFound}
 		}
 
 		return docs[0], nil
 	}
 
-	docs, err := c.fetchDocsByQuery(ctx, valOpts.tx, query.Limit(1))
+	// vector searches have their own limit
+	if query.nearest != nil {
+		query = query.FindNearest(query.nearest.path, query.nearest.vector, 1, query.nearest.measure)
+	} else {
+		query = query.Limit(1)
+	}
+
+	docs, err := c.fetchDocsByQuery(ctx, valOpts.tx, query)
 	if err != nil {
 		return Document[T]{}, err
 	}
 
 	if len(docs) == 0 {
-		return Document[T]{}, nil
+		return Document[T]{}, ErrNotFound
 	}
 
 	return docs[0], nil
@@ -331,25 +408,16 @@ func (c *CollectionRef[T]) Count(ctx context.Context, query Query) (int64, error
 		return 0, errors.New("firevault: nil CollectionRef")
 	}
 
-	if len(query.ids) > 0 {
-		return int64(len(query.ids)), nil
+	if query.nearest != nil {
+		return 0, errors.New("firevault: Count cannot be used with FindNearest")
 	}
 
-	builtQuery := c.buildQuery(query)
-	results, err := builtQuery.NewAggregationQuery().WithCount("all").Get(ctx)
+	results, err := c.Aggregate(ctx, query, []Aggregation{Count("all")})
 	if err != nil {
 		return 0, err
 	}
 
-	count, ok := results["all"]
-	if !ok {
-		return 0, errors.New("firestore: couldn't get alias for COUNT from results")
-	}
-
-	countValue := count.(*firestorepb.Value)
-	countInt := countValue.GetIntegerValue()
-
-	return countInt, nil
+	return results["all"].Int(), nil
 }
 
 // used to determine how to parse options
@@ -370,65 +438,157 @@ func (c *CollectionRef[T]) parseOptions(
 	method methodType,
 	opts ...Options,
 ) (validationOpts, string, firestore.Precondition, bool, []string) {
+	options := parseValidationOpts(c.path, method, opts...)
+
+	if len(opts) == 0 {
+		return options, "", nil, true, nil
+	}
+
+	passedOpts := opts[0]
+
+	if method == update && passedOpts.disableMerge {
+		options.deleteEmpty = true
+		return options, passedOpts.id, passedOpts.precondition, false, nil
+	}
+
+	if method == update && len(passedOpts.mergeFields) > 0 {
+		return options, passedOpts.id, passedOpts.precondition, true, passedOpts.mergeFields
+	}
+
+	return options, passedOpts.id, passedOpts.precondition, true, nil
+}
+
+// parse passed options into validation options
+func parseValidationOpts(collPath string, method methodType, opts ...Options) validationOpts {
 	if len(opts) == 0 {
-		return validationOpts{collPath: c.path, method: method}, "", nil, true, nil
+		return validationOpts{collPath: collPath, method: method}
 	}
 
-	// parse options
 	passedOpts := opts[0]
 	options := validationOpts{
-		collPath:           c.path,
+		collPath:           collPath,
 		method:             method,
 		skipValidation:     passedOpts.skipValidation,
 		skipValFields:      passedOpts.skipValFields,
 		skipValRules:       passedOpts.skipValRules,
 		emptyFieldsAllowed: passedOpts.allowEmptyFields,
 		modifyOriginal:     passedOpts.modifyOriginal,
+		rejectImmutable:    passedOpts.rejectImmutable,
 		tx:                 passedOpts.transaction,
+		options:            passedOpts,
 	}
 
 	if method == validate && passedOpts.method != "" {
 		options.method = passedOpts.method
 	}
 
-	if method == update && passedOpts.disableMerge {
-		options.deleteEmpty = true
-		return options, passedOpts.id, passedOpts.precondition, false, nil
+	if method == validate && passedOpts.id != "" {
+		options.docIDs = []string{passedOpts.id}
 	}
 
-	if method == update && len(passedOpts.mergeFields) > 0 {
-		return options, passedOpts.id, passedOpts.precondition, true, passedOpts.mergeFields
+	return options
+}
+
+// get orders of a Query, with struct paths resolved to stored paths
+func (c *CollectionRef[T]) resolveOrders(orders []order) ([]order, error) {
+	resolved := make([]order, len(orders))
+
+	for i, o := range orders {
+		resolved[i] = order{o.path, o.direction, false}
+
+		if o.byStruct {
+			path, _, err := c.connection.validator.resolveFieldPath(reflect.TypeFor[T](), o.path, true)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved[i].path = path
+		}
 	}
 
-	return options, passedOpts.id, passedOpts.precondition, true, nil
+	return resolved, nil
 }
 
 // build a new firestore query
-func (c *CollectionRef[T]) buildQuery(query Query) firestore.Query {
-	newQuery := c.ref.Query
+func (c *CollectionRef[T]) buildQuery(query Query) (firestore.Query, error) {
+	newQuery := c.baseQuery()
+	v := c.connection.validator
 
 	for _, filter := range query.filters {
-		newQuery = newQuery.Where(filter.path, filter.operator, filter.value)
+		if filter == nil {
+			return newQuery, errors.New("firevault: query filter cannot be nil")
+		}
+
+		entityFilter, err := filter.entityFilter(v, reflect.TypeFor[T]())
+		if err != nil {
+			return newQuery, err
+		}
+
+		newQuery = newQuery.WhereEntity(entityFilter)
+	}
+
+	if query.projected {
+		for _, path := range query.fields {
+			// catch typos, which would otherwise return empty documents
+			_, _, err := v.resolveFieldPath(reflect.TypeFor[T](), path, false)
+			if err != nil {
+				return newQuery, err
+			}
+		}
+
+		fields := query.fields
+
+		// documents of interface types are decoded based on their discriminator
+		if reflect.TypeFor[T]().Kind() == reflect.Interface && !slices.Contains(fields, DiscriminatorField) {
+			fields = append(slices.Clip(fields), DiscriminatorField)
+		}
+
+		newQuery = newQuery.Select(fields...)
 	}
 
-	for _, order := range query.orders {
+	orders, err := c.resolveOrders(query.orders)
+	if err != nil {
+		return newQuery, err
+	}
+
+	for _, order := range orders {
 		newQuery = newQuery.OrderBy(order.path, firestore.Direction(order.direction))
 	}
 
-	if len(query.startAt) > 0 {
-		newQuery = newQuery.StartAt(query.startAt...)
+	startAt, err := v.marshalQueryValues(query.startAt)
+	if err != nil {
+		return newQuery, err
 	}
 
-	if len(query.startAfter) > 0 {
-		newQuery = newQuery.StartAfter(query.startAfter...)
+	startAfter, err := v.marshalQueryValues(query.startAfter)
+	if err != nil {
+		return newQuery, err
+	}
+
+	endBefore, err := v.marshalQueryValues(query.endBefore)
+	if err != nil {
+		return newQuery, err
 	}
 
-	if len(query.endBefore) > 0 {
-		newQuery = newQuery.EndBefore(query.endBefore...)
+	endAt, err := v.marshalQueryValues(query.endAt)
+	if err != nil {
+		return newQuery, err
+	}
+
+	if len(startAt) > 0 {
+		newQuery = newQuery.StartAt(startAt...)
 	}
 
-	if len(query.endAt) > 0 {
-		newQuery = newQuery.EndAt(query.endAt...)
+	if len(startAfter) > 0 {
+		newQuery = newQuery.StartAfter(startAfter...)
+	}
+
+	if len(endBefore) > 0 {
+		newQuery = newQuery.EndBefore(endBefore...)
+	}
+
+	if len(endAt) > 0 {
+		newQuery = newQuery.EndAt(endAt...)
 	}
 
 	if query.limit > 0 {
@@ -443,7 +603,7 @@ func (c *CollectionRef[T]) buildQuery(query Query) firestore.Query {
 		newQuery = newQuery.Offset(query.offset)
 	}
 
-	return newQuery
+	return newQuery, nil
 }
 
 // prepares firestore updates based on merge logic
@@ -496,11 +656,112 @@ func (c *CollectionRef[T]) parseUpdates(
 	return updates
 }
 
+// update documents, validating data against each stored document
+func (c *CollectionRef[T]) updateWithPrevious(
+	ctx context.Context,
+	query Query,
+	data *T,
+	valOpts validationOpts,
+	precond firestore.Precondition,
+	merge bool,
+	mergeFields []string,
+) error {
+	var docs []Document[T]
+	var err error
+
+	// stored documents are always read in full
+	query.fields, query.projected = nil, false
+
+	if len(query.ids) > 0 {
+		docs, err = c.fetchDocsByID(ctx, valOpts.tx, query.ids)
+	} else {
+		docs, err = c.fetchDocsByQuery(ctx, valOpts.tx, query)
+	}
+	if err != nil {
+		return err
+	}
+
+	// ids without a stored document are still updated (and fail)
+	docIDs := query.ids
+	if len(docIDs) == 0 {
+		for _, doc := range docs {
+			docIDs = append(docIDs, c.docKey(doc))
+		}
+	}
+
+	if len(docIDs) == 0 {
+		return nil // no matching documents
+	}
+
+	prevDocs := make(map[string]*T, len(docs))
+	docPreconds := make(map[string]firestore.Precondition, len(docIDs))
+
+	for i := range docs {
+		docID := c.docKey(docs[i])
+		prevDocs[docID] = &docs[i].Data
+		docPreconds[docID] = precond
+
+		// outside a transaction, only write documents which haven't changed since they were read
+		// (a last update time precondition already guarantees that)
+		if valOpts.tx == nil && (precond == nil || precond == firestore.Exists) {
+			docPreconds[docID] = firestore.LastUpdateTime(docs[i].Metadata.UpdateTime)
+		}
+	}
+
+	docUpdates := make(map[string][]firestore.Update, len(docIDs))
+	var errs []error
+
+	for _, docID := range docIDs {
+		docOpts := valOpts
+		docOpts.docIDs = []string{docID}
+		docOpts.previous = reflect.Value{}
+
+		if prev, ok := prevDocs[docID]; ok {
+			docOpts.previous = reflect.ValueOf(prev).Elem()
+		}
+
+		dataMap, err := c.connection.validator.validate(ctx, data, docOpts)
+		if err != nil {
+			errs = append(errs, &DocError{docID, err})
+			continue
+		}
+
+		docUpdates[docID] = c.parseUpdates(dataMap, merge, mergeFields)
+	}
+
+	// don't apply any updates if validation failed for a document
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// documents have already been read
+	query = Query{ids: docIDs}
+
+	// perform transaction if provided opt
+	if valOpts.tx != nil {
+		return c.transacOperation(valOpts.tx, query, func(tx *firestore.Transaction, ref *firestore.DocumentRef, docID string) error {
+			if precond != nil {
+				return tx.Update(ref, docUpdates[docID], precond)
+			}
+
+			return tx.Update(ref, docUpdates[docID])
+		})
+	}
+
+	return c.bulkOperation(ctx, query, func(bw *firestore.BulkWriter, ref *firestore.DocumentRef, docID string) (*firestore.BulkWriterJob, error) {
+		if docPreconds[docID] != nil {
+			return bw.Update(ref, docUpdates[docID], docPreconds[docID])
+		}
+
+		return bw.Update(ref, docUpdates[docID])
+	})
+}
+
 // perform an operation within a started transaction
 func (c *CollectionRef[T]) transacOperation(
 	tx *firestore.Transaction,
 	query Query,
-	operation func(*firestore.Transaction, string) error,
+	operation func(*firestore.Transaction, *firestore.DocumentRef, string) error,
 ) error {
 	if tx == nil {
 		return errors.New("firevault: no transaction provided")
@@ -515,7 +776,7 @@ func (c *CollectionRef[T]) transacOperation(
 		}
 
 		for _, doc := range docs {
-			docIDs = append(docIDs, doc.ID)
+			docIDs = append(docIDs, c.docKey(doc))
 		}
 	}
 
@@ -523,15 +784,15 @@ func (c *CollectionRef[T]) transacOperation(
 		return nil // no matching documents
 	}
 
-	var mu sync.Mutex
 	var errs []error
 
 	for _, docID := range docIDs {
-		err := operation(tx, docID)
+		ref, err := c.doc(docID)
+		if err == nil {
+			err = operation(tx, ref, docID)
+		}
 		if err != nil {
-			mu.Lock()
-			errs = append(errs, errors.New(err.Error()+" (docID: "+docID+")"))
-			mu.Unlock()
+			errs = append(errs, &DocError{docID, wrapFirestoreError(err)})
 		}
 	}
 
@@ -542,7 +803,7 @@ func (c *CollectionRef[T]) transacOperation(
 func (c *CollectionRef[T]) bulkOperation(
 	ctx context.Context,
 	query Query,
-	operation func(*firestore.BulkWriter, string) error,
+	operation func(*firestore.BulkWriter, *firestore.DocumentRef, string) (*firestore.BulkWriterJob, error),
 ) error {
 	bulkWriter := c.connection.client.BulkWriter(ctx)
 	defer bulkWriter.End()
@@ -556,7 +817,7 @@ func (c *CollectionRef[T]) bulkOperation(
 		}
 
 		for _, doc := range docs {
-			docIDs = append(docIDs, doc.ID)
+			docIDs = append(docIDs, c.docKey(doc))
 		}
 	}
 
@@ -564,21 +825,39 @@ func (c *CollectionRef[T]) bulkOperation(
 		return nil // no matching documents
 	}
 
-	var mu sync.Mutex
 	var errs []error
+	jobs := make([]*firestore.BulkWriterJob, len(docIDs))
 
-	for _, docID := range docIDs {
-		err := operation(bulkWriter, docID)
+	for i, docID := range docIDs {
+		ref, err := c.doc(docID)
+		if err != nil {
+			errs = append(errs, &DocError{docID, err})
+			continue
+		}
+
+		job, err := operation(bulkWriter, ref, docID)
 		if err != nil {
-			mu.Lock()
-			errs = append(errs, errors.New(err.Error()+" (docID: "+docID+")"))
-			mu.Unlock()
+			errs = append(errs, &DocError{docID, wrapFirestoreError(err)})
+			continue
 		}
+
+		jobs[i] = job
 	}
 
 	// wait for all operations to complete
 	bulkWriter.Flush()
 
+	// operations only fail in Firestore once they're sent
+	for i, job := range jobs {
+		if job == nil {
+			continue
+		}
+
+		if _, err := job.Results(); err != nil {
+			errs = append(errs, &DocError{docIDs[i], wrapFirestoreError(err)})
+		}
+	}
+
 	return errors.Join(errs...)
 }
 
@@ -588,12 +867,42 @@ func (c *CollectionRef[T]) fetchDocsByID(
 	tx *Transaction,
 	ids []string,
 ) ([]Document[T], error) {
+	snapshots, err := c.fetchSnapshotsByID(ctx, tx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]Document[T], 0, len(snapshots))
+
+	for _, docSnap := range snapshots {
+		doc, err := c.snapshotDoc(docSnap, "") // distance only set by vector searches
+		if err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
+
+// fetch snapshots of existing documents, based on provided ids
+func (c *CollectionRef[T]) fetchSnapshotsByID(
+	ctx context.Context,
+	tx *Transaction,
+	ids []string,
+) ([]*firestore.DocumentSnapshot, error) {
 	const batchSize = 100
 	var docRefs []*firestore.DocumentRef
-	var docs []Document[T]
+	var existing []*firestore.DocumentSnapshot
 
 	for _, docID := range ids {
-		docRefs = append(docRefs, c.ref.Doc(docID))
+		ref, err := c.doc(docID)
+		if err != nil {
+			return nil, &DocError{docID, err}
+		}
+
+		docRefs = append(docRefs, ref)
 	}
 
 	for i := 0; i < len(docRefs); i += batchSize {
@@ -613,37 +922,27 @@ func (c *CollectionRef[T]) fetchDocsByID(
 			snapshots, err = c.connection.client.GetAll(ctx, batchRefs)
 		}
 		if err != nil {
-			return nil, err
+			return nil, wrapFirestoreError(err)
 		}
 
 		for _, docSnap := range snapshots {
-			if !docSnap.Exists() {
-				continue
+			if docSnap.Exists() {
+				existing = append(existing, docSnap)
 			}
+		}
+	}
 
-			var doc T
-
-			err = docSnap.DataTo(&doc)
-			if err != nil {
-				return nil, err
-			}
+	return existing, nil
+}
 
-			docs = append(
-				docs,
-				Document[T]{
-					docSnap.Ref.ID,
-					doc,
-					metadata{
-						docSnap.CreateTime,
-						docSnap.UpdateTime,
-						docSnap.ReadTime,
-					},
-				},
-			)
-		}
+// decode document's data, using custom types and registered types for interface values
+func (c *CollectionRef[T]) decodeDoc(docSnap *firestore.DocumentSnapshot, doc *T) error {
+	v := c.connection.validator
+	if !v.usesDecoder(reflect.TypeFor[T]()) {
+		return docSnap.DataTo(doc)
 	}
 
-	return docs, nil
+	return v.decodeValue(reflect.ValueOf(doc).Elem(), docSnap.Data())
 }
 
 // fetch documents based on provided Query
@@ -652,17 +951,24 @@ func (c *CollectionRef[T]) fetchDocsByQuery(
 	tx *Transaction,
 	query Query,
 ) ([]Document[T], error) {
-	builtQuery := c.buildQuery(query)
+	docs, _, err := c.fetchSnapshotsByQuery(ctx, tx, query)
+	return docs, err
+}
 
-	var iter *firestore.DocumentIterator
-	if tx != nil {
-		iter = tx.Documents(builtQuery) // use transaction
-	} else {
-		iter = builtQuery.Documents(ctx)
+// fetch documents (and their snapshots) based on provided Query
+func (c *CollectionRef[T]) fetchSnapshotsByQuery(
+	ctx context.Context,
+	tx *Transaction,
+	query Query,
+) ([]Document[T], []*firestore.DocumentSnapshot, error) {
+	iter, distanceField, err := c.queryIterator(ctx, tx, query)
+	if err != nil {
+		return nil, nil, err
 	}
 	defer iter.Stop()
 
 	var docs []Document[T]
+	var snapshots []*firestore.DocumentSnapshot
 
 	for {
 		docSnap, err := iter.Next()
@@ -670,29 +976,160 @@ func (c *CollectionRef[T]) fetchDocsByQuery(
 			break
 		}
 		if err != nil {
-			return nil, err
+			return nil, nil, wrapFirestoreError(err)
+		}
+
+		doc, err := c.snapshotDoc(docSnap, distanceField)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		docs = append(docs, doc)
+		snapshots = append(snapshots, docSnap)
+	}
+
+	return docs, snapshots, nil
+}
+
+// get Firestore iterator of documents matching provided Query, and the field holding vector distances
+func (c *CollectionRef[T]) queryIterator(
+	ctx context.Context,
+	tx *Transaction,
+	query Query,
+) (*firestore.DocumentIterator, string, error) {
+	builtQuery, err := c.buildQuery(query)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch {
+	case query.nearest != nil:
+		if tx != nil {
+			return nil, "", errors.New("firevault: FindNearest cannot be used inside a transaction")
 		}
 
-		var doc T
+		distanceField := query.nearest.resultField
+		if distanceField == "" {
+			distanceField = defaultDistanceField
+		}
 
-		err = docSnap.DataTo(&doc)
+		return c.buildVectorQuery(builtQuery, query.nearest, distanceField).Documents(ctx), distanceField, nil
+	case tx != nil:
+		return tx.Documents(builtQuery), "", nil // use transaction
+	}
+
+	return builtQuery.Documents(ctx), "", nil
+}
+
+// decode a fetched document snapshot
+func (c *CollectionRef[T]) snapshotDoc(docSnap *firestore.DocumentSnapshot, distanceField string) (Document[T], error) {
+	var doc T
+
+	err := c.decodeDoc(docSnap, &doc)
+	if err != nil {
+		return Document[T]{}, err
+	}
+
+	var distance float64
+	if distanceField != "" {
+		distance, err = c.distance(docSnap, distanceField)
 		if err != nil {
-			return nil, err
+			return Document[T]{}, err
 		}
+	}
 
-		docs = append(
-			docs,
-			Document[T]{
-				docSnap.Ref.ID,
-				doc,
-				metadata{
-					docSnap.CreateTime,
-					docSnap.UpdateTime,
-					docSnap.ReadTime,
-				},
-			},
-		)
+	return Document[T]{
+		docSnap.Ref.ID,
+		doc,
+		metadata{
+			docSnap.CreateTime,
+			docSnap.UpdateTime,
+			docSnap.ReadTime,
+			distance,
+		},
+		relativePath(docSnap.Ref),
+	}, nil
+}
+
+// get reference of a document, by its ID (or its path, in collection groups)
+func (c *CollectionRef[T]) doc(key string) (*firestore.DocumentRef, error) {
+	if c.group != nil {
+		// paths must point to a document of the group's collections
+		segments := strings.Split(key, "/")
+		if len(segments)%2 != 0 || slices.Contains(segments, "") || segments[len(segments)-2] != c.path {
+			return nil, errors.New("firevault: invalid document path " + key)
+		}
+
+		return c.connection.client.Doc(key), nil
 	}
 
-	return docs, nil
+	return c.ref.Doc(key), nil
+}
+
+// get key identifying a fetched document, i.e. its ID (or its path, in collection groups)
+func (c *CollectionRef[T]) docKey(doc Document[T]) string {
+	if c.group != nil {
+		return doc.Path
+	}
+
+	return doc.ID
+}
+
+// get the query of all documents in the collection (or collection group)
+func (c *CollectionRef[T]) baseQuery() firestore.Query {
+	if c.group != nil {
+		return c.group.Query
+	}
+
+	return c.ref.Query
+}
+
+// get path of a document, relative to the database
+func relativePath(ref *firestore.DocumentRef) string {
+	_, path, _ := strings.Cut(ref.Path, "/documents/")
+	return path
+}
+
+// build vector search, based on provided Query
+func (c *CollectionRef[T]) buildVectorQuery(
+	query firestore.Query,
+	n *nearest,
+	distanceField string,
+) firestore.VectorQuery {
+	// named vector types (other than Firestore's) aren't accepted
+	vector := n.vector
+	if val := reflect.ValueOf(vector); val.Kind() == reflect.Slice {
+		switch val.Type().Elem().Kind() {
+		case reflect.Float32:
+			vector = val.Convert(reflect.TypeFor[[]float32]()).Interface()
+		case reflect.Float64:
+			vector = val.Convert(reflect.TypeFor[[]float64]()).Interface()
+		}
+	}
+
+	return query.FindNearestPath(
+		firestore.FieldPath(strings.Split(n.path, ".")),
+		vector,
+		n.limit,
+		n.measure,
+		&firestore.FindNearestOptions{
+			DistanceThreshold:   n.threshold,
+			DistanceResultField: distanceField,
+		},
+	)
+}
+
+// get document's vector distance, as output by Firestore
+func (c *CollectionRef[T]) distance(docSnap *firestore.DocumentSnapshot, field string) (float64, error) {
+	value, err := docSnap.DataAtPath(firestore.FieldPath{field})
+	if err != nil {
+		return 0, err
+	}
+
+	distance, ok := value.(float64)
+	if !ok {
+		return 0, fmt.Errorf("firevault: invalid distance value %v", value)
+	}
+
+	return distance, nil
 }
@@ -0,0 +1,270 @@
+package firevault
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// A CollectionGroupRef holds a reference to all
+// Firestore collections with the same ID (e.g. the
+// "comments" subcollection of every post), and allows
+// for the fetching and modifying (with validation) of
+// documents in them.
+//
+// Fetched documents hold their full Path, which
+// identifies them within the group. When using the ID
+// method of a Query, document paths must be provided,
+// instead of IDs. Paths of documents outside the group's
+// collections are rejected, returning an error.
+//
+// CollectionGroupRef instances are lightweight and
+// safe to create repeatedly.
+type CollectionGroupRef[T interface{}] struct {
+	collection *CollectionRef[T]
+}
+
+// Create a new CollectionGroupRef instance.
+//
+// A CollectionGroupRef holds a reference to all
+// Firestore collections with the same ID, and allows
+// for the fetching and modifying (with validation) of
+// documents in them.
+//
+// The collectionID argument is the ID shared by the
+// collections (e.g. "comments"), and must not contain
+// a slash.
+//
+// Returns nil if collectionID contains a slash, or is
+// empty.
+func CollectionGroup[T interface{}](connection *Connection, collectionID string) *CollectionGroupRef[T] {
+	if connection == nil || connection.client == nil {
+		return nil
+	}
+
+	if !isValidID(collectionID) {
+		return nil
+	}
+
+	group := connection.client.CollectionGroup(collectionID)
+
+	return &CollectionGroupRef[T]{&CollectionRef[T]{connection, nil, collectionID, group}}
+}
+
+// Update all Firestore documents which match
+// provided Query (after data validation), using
+// their paths.
+//
+// It works in the same way as CollectionRef's
+// Update method, with document paths (instead of
+// IDs) made available to validators.
+func (g *CollectionGroupRef[T]) Update(ctx context.Context, query Query, data *T, opts ...Options) error {
+	if g == nil {
+		return errors.New("firevault: nil CollectionGroupRef")
+	}
+
+	return g.collection.Update(ctx, query, data, opts...)
+}
+
+// Delete all Firestore documents which match
+// provided Query, using their paths.
+//
+// It works in the same way as CollectionRef's
+// Delete method. When using the Cascade option,
+// the subcollections registered for the group's
+// collection ID are deleted too.
+func (g *CollectionGroupRef[T]) Delete(ctx context.Context, query Query, opts ...Options) error {
+	if g == nil {
+		return errors.New("firevault: nil CollectionGroupRef")
+	}
+
+	return g.collection.Delete(ctx, query, opts...)
+}
+
+// Find all Firestore documents which match
+// provided Query.
+//
+// To use inside a transaction, pass a transaction
+// instance via Options.
+func (g *CollectionGroupRef[T]) Find(ctx context.Context, query Query, opts ...Options) ([]Document[T], error) {
+	if g == nil {
+		return nil, errors.New("firevault: nil CollectionGroupRef")
+	}
+
+	return g.collection.Find(ctx, query, opts...)
+}
+
+// Find the first Firestore document which
+// matches provided Query.
+//
+// Returns an empty Document[T] (empty ID
+// string and zero-value T Data), and
+// ErrNotFound if no documents are found.
+//
+// To use inside a transaction, pass a transaction
+// instance via Options.
+func (g *CollectionGroupRef[T]) FindOne(ctx context.Context, query Query, opts ...Options) (Document[T], error) {
+	if g == nil {
+		return Document[T]{}, errors.New("firevault: nil CollectionGroupRef")
+	}
+
+	return g.collection.FindOne(ctx, query, opts...)
+}
+
+// Find number of Firestore documents which
+// match provided Query.
+func (g *CollectionGroupRef[T]) Count(ctx context.Context, query Query) (int64, error) {
+	if g == nil {
+		return 0, errors.New("firevault: nil CollectionGroupRef")
+	}
+
+	return g.collection.Count(ctx, query)
+}
+
+// Compute aggregations (counts, sums and averages)
+// over the Firestore documents which match provided
+// Query, in a single request.
+//
+// It works in the same way as CollectionRef's
+// Aggregate method.
+func (g *CollectionGroupRef[T]) Aggregate(
+	ctx context.Context,
+	query Query,
+	aggregations []Aggregation,
+	opts ...Options,
+) (AggregationResult, error) {
+	if g == nil {
+		return nil, errors.New("firevault: nil CollectionGroupRef")
+	}
+
+	return g.collection.Aggregate(ctx, query, aggregations, opts...)
+}
+
+// Check whether any Firestore document matches
+// provided Query.
+//
+// To use inside a transaction, pass a transaction
+// instance via Options.
+func (g *CollectionGroupRef[T]) Exists(ctx context.Context, query Query, opts ...Options) (bool, error) {
+	if g == nil {
+		return false, errors.New("firevault: nil CollectionGroupRef")
+	}
+
+	return g.collection.Exists(ctx, query, opts...)
+}
+
+// Documents returns an iterator over the Firestore
+// documents which match provided Query.
+//
+// It works in the same way as CollectionRef's
+// Documents method.
+func (g *CollectionGroupRef[T]) Documents(ctx context.Context, query Query, opts ...Options) *DocumentIterator[T] {
+	if g == nil {
+		return &DocumentIterator[T]{ctx: ctx, err: errors.New("firevault: nil CollectionGroupRef")}
+	}
+
+	return g.collection.Documents(ctx, query, opts...)
+}
+
+// All returns an iterator over the Firestore documents
+// which match provided Query, to be used in a for-range
+// loop.
+//
+// It works in the same way as CollectionRef's All
+// method.
+func (g *CollectionGroupRef[T]) All(ctx context.Context, query Query, opts ...Options) iter.Seq2[Document[T], error] {
+	if g == nil {
+		return func(yield func(Document[T], error) bool) {
+			yield(Document[T]{}, errors.New("firevault: nil CollectionGroupRef"))
+		}
+	}
+
+	return g.collection.All(ctx, query, opts...)
+}
+
+// Paginate fetches a page of Firestore documents which
+// match provided Query, returning at most pageSize
+// documents.
+//
+// It works in the same way as CollectionRef's Paginate
+// method, with results ordered by document path
+// (instead of ID) after the Query's other orders.
+func (g *CollectionGroupRef[T]) Paginate(
+	ctx context.Context,
+	query Query,
+	pageSize int,
+	opts ...Options,
+) (Page[T], error) {
+	if g == nil {
+		return Page[T]{}, errors.New("firevault: nil CollectionGroupRef")
+	}
+
+	return g.collection.Paginate(ctx, query, pageSize, opts...)
+}
+
+// Watch listens to the Firestore documents which match
+// provided Query, calling handler with a new Snapshot
+// each time they change.
+//
+// It works in the same way as CollectionRef's Watch
+// method.
+func (g *CollectionGroupRef[T]) Watch(ctx context.Context, query Query, handler func(Snapshot[T]) error) error {
+	if g == nil {
+		return errors.New("firevault: nil CollectionGroupRef")
+	}
+
+	return g.collection.Watch(ctx, query, handler)
+}
+
+// WatchDocument listens to a single Firestore document,
+// identified by its path, calling handler with a Change
+// each time it's created, modified or deleted.
+//
+// It works in the same way as CollectionRef's
+// WatchDocument method.
+func (g *CollectionGroupRef[T]) WatchDocument(
+	ctx context.Context,
+	path string,
+	handler func(Change[T]) error,
+) error {
+	if g == nil {
+		return errors.New("firevault: nil CollectionGroupRef")
+	}
+
+	return g.collection.WatchDocument(ctx, path, handler)
+}
+
+// ParentCollections returns the IDs of the collections
+// for which the group's collections are registered as
+// subcollections, using the Connection's
+// RegisterSubCollection method.
+func (g *CollectionGroupRef[T]) ParentCollections() []string {
+	if g == nil {
+		return nil
+	}
+
+	return g.collection.connection.ParentCollections(g.collection.path)
+}
+
+// InHierarchy reports whether a fetched document
+// belongs to the registered hierarchy, i.e. whether its
+// parent document is in one of the ParentCollections.
+//
+// Useful to skip documents of unrelated collections
+// with the same ID (e.g. "comments" of tickets, when
+// reading the comments of posts). Returns false for
+// top-level documents.
+func (g *CollectionGroupRef[T]) InHierarchy(doc Document[T]) bool {
+	if g == nil {
+		return false
+	}
+
+	segments := strings.Split(doc.Path, "/")
+	if len(segments) < 4 {
+		return false
+	}
+
+	return slices.Contains(g.ParentCollections(), segments[len(segments)-4])
+}
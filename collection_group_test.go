@@ -0,0 +1,99 @@
+package firevault
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+)
+
+func TestCollectionGroup(t *testing.T) {
+	paths := map[string][]string{
+		"posts/p1":                   {},
+		"posts/p1/comments/c1":       {"p1"},
+		"users/u1/posts/p1/likes/l1": {"u1", "p1"},
+	}
+
+	for path, want := range paths {
+		doc := Document[map[string]interface{}]{ID: path[strings.LastIndex(path, "/")+1:], Path: path}
+		if got := doc.ParentIDs(); !reflect.DeepEqual(got, want) {
+			t.Errorf("ParentIDs() of %s = %v, want %v", path, got, want)
+		}
+	}
+
+	if CollectionGroup[map[string]interface{}](&Connection{}, "comments") != nil {
+		t.Errorf("Expected nil CollectionGroupRef without a Firestore client")
+	}
+
+	// tokens of a collection can't be used by a collection group with the same ID
+	connection := &Connection{Validator: NewValidator(), pageTokenKey: []byte(strings.Repeat("k", 32))}
+	collection := &CollectionRef[map[string]interface{}]{connection: connection, path: "comments"}
+	group := &CollectionRef[map[string]interface{}]{
+		connection: connection,
+		path:       "comments",
+		group:      &firestore.CollectionGroupRef{},
+	}
+
+	orders := []order{{DocumentID, Asc, false}}
+
+	raw, err := collection.newPageToken([]interface{}{"c1"}, orders, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := group.decodePageToken(raw, orders); err == nil {
+		t.Errorf("Expected error for token of another collection")
+	}
+
+	var nilGroup *CollectionGroupRef[map[string]interface{}]
+	for _, err := range nilGroup.All(context.Background(), NewQuery()) {
+		if err == nil {
+			t.Errorf("Expected error for nil CollectionGroupRef")
+		}
+	}
+}
+
+func TestCollectionGroupPaths(t *testing.T) {
+	type Comment struct {
+		Text string `firevault:"text"`
+	}
+
+	fake := &fakeFirestore{docs: map[string]*pb.Document{}}
+	comments := CollectionGroup[Comment](newFakeConnection(t, fake), "comments")
+	ctx := context.Background()
+
+	if _, err := comments.collection.doc("posts/p1/comments/c1"); err != nil {
+		t.Errorf("Unexpected error for document of the group: %v", err)
+	}
+
+	paths := map[string]string{
+		"Other collection": "users/abc",
+		"Odd segments":     "posts/p1/comments",
+		"Empty segment":    "posts//comments/c1",
+	}
+
+	for name, path := range paths {
+		t.Run(name, func(t *testing.T) {
+			errs := []error{
+				comments.Update(ctx, NewQuery().ID(path), &Comment{"hi"}),
+				comments.Delete(ctx, NewQuery().ID(path)),
+			}
+
+			_, err := comments.Find(ctx, NewQuery().ID(path))
+			errs = append(errs, err)
+
+			for _, err := range errs {
+				if err == nil || !strings.Contains(err.Error(), "firevault: invalid document path") {
+					t.Errorf("Expected invalid document path error, got %v", err)
+				}
+			}
+
+			if len(fake.writes) != 0 {
+				t.Errorf("Expected no writes, got %v", fake.writes)
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@ package firevault
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
+	"slices"
 
 	"cloud.google.com/go/firestore"
 )
@@ -19,8 +21,10 @@ import (
 // Using multiple instances defeats the
 // purpose of caching.
 type Connection struct {
-	client    *firestore.Client
-	validator *validator
+	*Validator
+	client         *firestore.Client
+	pageTokenKey   []byte
+	subCollections map[string][]string // child collection IDs, by parent collection ID
 }
 
 // Create a new Connection instance.
@@ -28,6 +32,10 @@ type Connection struct {
 // Connection provides access to Firevault
 // services.
 //
+// It embeds a Validator, so rules registered
+// with the Connection apply to all of its
+// collections.
+//
 // It is designed to be thread-safe and used
 // as a singleton instance.
 //
@@ -37,44 +45,67 @@ type Connection struct {
 // Using multiple instances defeats the
 // purpose of caching.
 func Connect(ctx context.Context, projectID string) (*Connection, error) {
-	val := newValidator()
+	val := NewValidator()
 
 	client, err := firestore.NewClient(ctx, projectID)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Connection{client, val}, nil
+	// page tokens are signed with a random key, unless one is set
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	return &Connection{val, client, key, map[string][]string{}}, nil
 }
 
-// Close closes the connection to Firevault.
+// Set the key used to sign (and verify) the page
+// tokens returned by Paginate.
 //
-// Should be invoked when the connection is
-// no longer required.
+// By default, a random key is generated for each
+// Connection, so tokens are only valid for the
+// Connection which created them. Setting the same
+// key on multiple Connections (e.g. on every
+// instance of a server) allows them to accept each
+// other's tokens.
 //
-// Close need not be called at program exit.
-func (c *Connection) Close() error {
-	if c == nil || c.client == nil {
-		return errors.New("firevault: nil Connection or Firestore Client")
+// The key must be at least 32 bytes long, and
+// should be kept secret.
+//
+// Setting the key is not thread-safe; it is
+// intended that it be set prior to any pagination.
+func (c *Connection) SetPageTokenKey(key []byte) error {
+	if c == nil {
+		return errors.New("firevault: nil Connection")
 	}
 
-	return c.client.Close()
+	if len(key) < 32 {
+		return errors.New("firevault: page token key must be at least 32 bytes long")
+	}
+
+	c.pageTokenKey = slices.Clone(key)
+	return nil
 }
 
-// Register a new validation rule.
-//
-// If a validation rule with the same name
-// already exists, the previous one will be replaced.
+// Validate and transform provided data, using the
+// Connection's Validator.
 //
-// If the validation name includes a method-specific
-// suffix ("_create", "_update", or "_validate"),
-// the rule will be applied exclusively during
-// calls to the corresponding method type and
-// ignored for others.
+// See Validator's Validate method for details.
+func (c *Connection) Validate(ctx context.Context, data interface{}, opts ...Options) error {
+	if c == nil {
+		return errors.New("firevault: nil Connection")
+	}
+
+	return c.Validator.Validate(ctx, data, opts...)
+}
+
+// Register a new validation rule with the
+// Connection's Validator.
 //
-// Registering such functions is not thread-safe;
-// it is intended that all rules be registered,
-// prior to any validation.
+// See Validator's RegisterValidation method for
+// details.
 func (c *Connection) RegisterValidation(
 	name string,
 	validation Validation,
@@ -84,34 +115,14 @@ func (c *Connection) RegisterValidation(
 		return errors.New("firevault: nil Connection")
 	}
 
-	var nilCallable bool
-	if len(runOnNil) > 0 {
-		nilCallable = runOnNil[0]
-	}
-
-	return c.validator.registerValidation(
-		name,
-		validation.toValFuncInternal(),
-		false,
-		nilCallable,
-	)
+	return c.Validator.RegisterValidation(name, validation, runOnNil...)
 }
 
-// Register a new transformation rule.
-//
-// If a transformation rule with the same name
-// already exists, the previous one will be replaced.
-//
-// If the transformation name includes a
-// method-specific suffix
-// ("_create", "_update", or "_validate"),
-// the rule will be applied exclusively during
-// calls to the corresponding method type and
-// ignored for others.
+// Register a new transformation rule with the
+// Connection's Validator.
 //
-// Registering such functions is not thread-safe;
-// it is intended that all rules be registered,
-// prior to any validation.
+// See Validator's RegisterTransformation method
+// for details.
 func (c *Connection) RegisterTransformation(
 	name string,
 	transformation Transformation,
@@ -121,38 +132,72 @@ func (c *Connection) RegisterTransformation(
 		return errors.New("firevault: nil Connection")
 	}
 
-	var nilCallable bool
-	if len(runOnNil) > 0 {
-		nilCallable = runOnNil[0]
+	return c.Validator.RegisterTransformation(name, transformation, runOnNil...)
+}
+
+// Register an error formatter with the
+// Connection's Validator.
+//
+// See Validator's RegisterErrorFormatter method
+// for details.
+func (c *Connection) RegisterErrorFormatter(errorFormatter ErrorFormatterFunc) error {
+	if c == nil {
+		return errors.New("firevault: nil Connection")
 	}
 
-	return c.validator.registerTransformation(
-		name,
-		transformation.toTranFuncInternal(),
-		false,
-		nilCallable,
-	)
+	return c.Validator.RegisterErrorFormatter(errorFormatter)
 }
 
-// Register a new error formatter.
+// Register a transition graph with the
+// Connection's Validator.
 //
-// Error formatters are used to generate a custom,
-// user-friendly error message, whenever a
-// FieldError is created (during a failed validation).
+// See Validator's RegisterTransitions method for
+// details.
+func (c *Connection) RegisterTransitions(name string, transitions map[string][]string) error {
+	if c == nil {
+		return errors.New("firevault: nil Connection")
+	}
+
+	return c.Validator.RegisterTransitions(name, transitions)
+}
+
+// Register a schema rule with the Connection's
+// Validator.
 //
-// If none are registered, or if a formatter returns
-// a nil error, an instance of a FieldError will be
-// returned instead.
+// See Validator's RegisterSchemaRule method for
+// details.
+func (c *Connection) RegisterSchemaRule(name string, schemaRule SchemaRuleFunc) error {
+	if c == nil {
+		return errors.New("firevault: nil Connection")
+	}
+
+	return c.Validator.RegisterSchemaRule(name, schemaRule)
+}
+
+// Register a concrete type with the Connection's
+// Validator.
 //
-// Registering error formatters is not thread-safe;
-// it is intended that all such functions
-// be registered, prior to any validation.
-func (c *Connection) RegisterErrorFormatter(errorFormatter ErrorFormatterFunc) error {
+// See Validator's RegisterType method for details.
+func (c *Connection) RegisterType(key string, value interface{}) error {
 	if c == nil {
 		return errors.New("firevault: nil Connection")
 	}
 
-	return c.validator.registerErrorFormatter(errorFormatter)
+	return c.Validator.RegisterType(key, value)
+}
+
+// Close closes the connection to Firevault.
+//
+// Should be invoked when the connection is
+// no longer required.
+//
+// Close need not be called at program exit.
+func (c *Connection) Close() error {
+	if c == nil || c.client == nil {
+		return errors.New("firevault: nil Connection or Firestore Client")
+	}
+
+	return c.client.Close()
 }
 
 // Run a Firestore transaction, ensuring all
@@ -173,10 +218,10 @@ func (c *Connection) RegisterErrorFormatter(errorFormatter ErrorFormatterFunc) e
 // part of the transaction.
 //
 // Returns an error if the transaction fails after
-// all retries.
+// all retries (e.g. ErrAborted, due to contention).
 func (c *Connection) RunTransaction(
 	ctx context.Context,
 	fn func(ctx context.Context, tx *Transaction) error,
 ) error {
-	return c.client.RunTransaction(ctx, fn)
+	return wrapFirestoreError(c.client.RunTransaction(ctx, fn))
 }
@@ -0,0 +1,233 @@
+package firevault
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Valuer is implemented by types which can
+// marshal themselves into a Firestore-compatible
+// value (e.g. a string, number, or map).
+//
+// The returned value is stored in place of the
+// original one, and is the value checked by the
+// built-in "min", "max" and "email" validations.
+type Valuer interface {
+	FirestoreValue() (interface{}, error)
+}
+
+// Scanner is implemented by types which can
+// unmarshal themselves from a value, as
+// returned by Firestore (i.e. as marshalled by
+// the type's Valuer).
+//
+// It must be implemented using a pointer
+// receiver.
+type Scanner interface {
+	ScanFirestore(value interface{}) error
+}
+
+// holds marshal and unmarshal functions of a custom type
+type customType struct {
+	value func(val reflect.Value) (interface{}, error)
+	scan  func(value interface{}) (reflect.Value, error)
+}
+
+var (
+	valuerType  = reflect.TypeFor[Valuer]()
+	scannerType = reflect.TypeFor[Scanner]()
+)
+
+// Register functions used to marshal values of a
+// custom type into Firestore-compatible values,
+// and to unmarshal them back.
+//
+// Useful for types which can't implement Valuer
+// and Scanner (e.g. types from other packages,
+// such as url.URL or netip.Addr). Registered
+// functions take precedence over the type's
+// Valuer and Scanner.
+//
+// If the same type is already registered, the
+// previous functions will be replaced.
+//
+// Registering such functions is not thread-safe;
+// it is intended that all types be registered,
+// prior to any validation.
+func RegisterCustomType[T interface{}](
+	validator *Validator,
+	value func(T) (interface{}, error),
+	scan func(interface{}) (T, error),
+) error {
+	if validator == nil {
+		return errors.New("firevault: nil Validator")
+	}
+
+	typ := reflect.TypeFor[T]()
+
+	if value == nil || scan == nil {
+		return errors.New("firevault: custom type " + typ.String() + " functions cannot be empty")
+	}
+
+	if typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Interface {
+		return errors.New("firevault: custom type " + typ.String() + " cannot be a pointer or interface")
+	}
+
+	validator.validator.customTypes[typ] = &customType{
+		value: func(val reflect.Value) (interface{}, error) {
+			return value(val.Interface().(T))
+		},
+		scan: func(raw interface{}) (reflect.Value, error) {
+			x, err := scan(raw)
+			return reflect.ValueOf(&x).Elem(), err
+		},
+	}
+
+	return nil
+}
+
+// check if values of type (or pointers to them) are marshalled by a Valuer or a registered custom type
+func (v *validator) isValuer(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	if _, ok := v.customTypes[typ]; ok {
+		return true
+	}
+
+	return typ.Implements(valuerType) || reflect.PointerTo(typ).Implements(valuerType)
+}
+
+// check if values of type are unmarshalled by a Scanner or a registered custom type
+func (v *validator) isScanner(typ reflect.Type) bool {
+	if _, ok := v.customTypes[typ]; ok {
+		return true
+	}
+
+	return reflect.PointerTo(typ).Implements(scannerType)
+}
+
+// get Firestore value of a custom type, reporting whether it is one
+func (v *validator) marshalValue(val reflect.Value) (interface{}, bool, error) {
+	if !val.IsValid() {
+		return nil, false, nil
+	}
+
+	if custom, ok := v.customTypes[val.Type()]; ok {
+		value, err := custom.value(val)
+		return value, true, err
+	}
+
+	if val.Type().Implements(valuerType) {
+		// avoid calling value receivers through a nil pointer
+		if val.Kind() == reflect.Pointer && val.IsNil() {
+			return nil, true, nil
+		}
+
+		value, err := val.Interface().(Valuer).FirestoreValue()
+		return value, true, err
+	}
+
+	if reflect.PointerTo(val.Type()).Implements(valuerType) {
+		// values held by interfaces aren't addressable
+		value, err := addressable(val).Addr().Interface().(Valuer).FirestoreValue()
+		return value, true, err
+	}
+
+	return nil, false, nil
+}
+
+// set value of a custom type from a Firestore value, reporting whether it is one
+func (v *validator) scanValue(dst reflect.Value, raw interface{}) (bool, error) {
+	if custom, ok := v.customTypes[dst.Type()]; ok {
+		val, err := custom.scan(raw)
+		if err != nil {
+			return true, err
+		}
+
+		dst.Set(val)
+		return true, nil
+	}
+
+	if dst.CanAddr() && reflect.PointerTo(dst.Type()).Implements(scannerType) {
+		return true, dst.Addr().Interface().(Scanner).ScanFirestore(raw)
+	}
+
+	return false, nil
+}
+
+// get field scope holding the marshalled value of a custom type
+func (v *validator) marshalledScope(fs *fieldScope) (*fieldScope, error) {
+	if !fs.value.IsValid() || !v.isValuer(fs.typ) {
+		return fs, nil
+	}
+
+	value, _, err := v.marshalValue(fs.value)
+	if err != nil {
+		return nil, err
+	}
+
+	marshalled := *fs
+	marshalled.value = reflect.ValueOf(value)
+	marshalled.kind = marshalled.value.Kind()
+	marshalled.typ = nil
+
+	if marshalled.value.IsValid() {
+		marshalled.typ = marshalled.value.Type()
+	}
+
+	return &marshalled, nil
+}
+
+// get Firestore values of custom and special types used in queries
+func (v *validator) marshalQueryValues(values []interface{}) ([]interface{}, error) {
+	if len(values) == 0 {
+		return values, nil
+	}
+
+	marshalled := make([]interface{}, len(values))
+
+	for i, value := range values {
+		val, err := v.marshalQueryValue(value)
+		if err != nil {
+			return nil, err
+		}
+
+		marshalled[i] = val
+	}
+
+	return marshalled, nil
+}
+
+// get Firestore value of a custom or special type (or slice of custom types) used in a query
+func (v *validator) marshalQueryValue(value interface{}) (interface{}, error) {
+	val := reflect.ValueOf(value)
+
+	// e.g. reference and geopoint values (which must be pointers)
+	if val.IsValid() && v.isSpecialType(val.Type()) {
+		return v.specialValue(val), nil
+	}
+
+	if marshalled, ok, err := v.marshalValue(val); ok {
+		return marshalled, err
+	}
+
+	// handle values of "in" and "array-contains-any" operators
+	if val.Kind() == reflect.Slice && val.Type().Elem().Kind() != reflect.Uint8 && v.isValuer(val.Type().Elem()) {
+		items := make([]interface{}, val.Len())
+
+		for i := range items {
+			marshalled, _, err := v.marshalValue(val.Index(i))
+			if err != nil {
+				return nil, err
+			}
+
+			items[i] = marshalled
+		}
+
+		return items, nil
+	}
+
+	return value, nil
+}
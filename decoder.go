@@ -0,0 +1,254 @@
+package firevault
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// check if type (or any nested type) can't be decoded by Firestore directly
+func (v *validator) usesDecoder(typ reflect.Type) bool {
+	if used, ok := v.decoderUsage.Load(typ); ok {
+		return used.(bool)
+	}
+
+	used := v.typeNeedsDecoder(typ, map[reflect.Type]bool{})
+	v.decoderUsage.Store(typ, used)
+
+	return used
+}
+
+// recursively check if type has interface-typed fields (holding registered types), custom types, special type values,
+// or fields which Firestore would decode using a different name than Firevault's
+func (v *validator) typeNeedsDecoder(typ reflect.Type, visited map[reflect.Type]bool) bool {
+	var isPointer bool
+
+	for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice ||
+		typ.Kind() == reflect.Array || typ.Kind() == reflect.Map {
+		if v.isScanner(typ) {
+			return true
+		}
+
+		isPointer = typ.Kind() == reflect.Pointer
+		typ = typ.Elem()
+	}
+
+	if v.isScanner(typ) {
+		return true
+	}
+
+	// references and geopoints are only decoded into pointers by Firestore
+	if (typ == docRefType || typ == latLngType) && !isPointer {
+		return true
+	}
+
+	if typ.Kind() == reflect.Interface {
+		return len(v.types) > 0
+	}
+
+	if typ.Kind() != reflect.Struct || visited[typ] {
+		return false
+	}
+
+	visited[typ] = true
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		tag := field.Tag.Get("firevault")
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		if !v.isDecodedByName(field) {
+			return true
+		}
+
+		if v.typeNeedsDecoder(field.Type, visited) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// check if Firestore decodes a field (or the fields of an inlined struct) by its Firevault name
+func (v *validator) isDecodedByName(field reflect.StructField) bool {
+	fsName, _, _ := strings.Cut(field.Tag.Get("firestore"), ",")
+	if fsName == "-" {
+		return false
+	}
+
+	// only embedded structs without a name have their fields promoted by Firestore
+	if v.isInlineField(field) {
+		return field.Anonymous && fsName == ""
+	}
+
+	name := v.parseTag(field.Tag.Get("firevault"))[0]
+	if name == "" {
+		name = field.Name
+	}
+
+	if fsName == "" {
+		fsName = field.Name
+	}
+
+	// Firestore matches names case-insensitively
+	return strings.EqualFold(name, fsName)
+}
+
+// decode document data into a struct, using Firevault field names
+func (v *validator) decodeStruct(dst reflect.Value, data map[string]interface{}) error {
+	sd, ok := v.cache.get(dst.Type())
+	if !ok {
+		var err error
+		sd, err = v.extractStructData(&fieldScope{typ: dst.Type(), value: dst})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, fs := range sd.fields {
+		raw, ok := data[fs.field]
+		if !ok {
+			continue
+		}
+
+		err := v.decodeValue(v.allocFieldByIndex(dst, fs.index), raw)
+		if err != nil {
+			return fmt.Errorf("%w - %s", err, fs.structField)
+		}
+	}
+
+	return nil
+}
+
+// decode a document value into a Go value, using custom types and the discriminator for interfaces
+func (v *validator) decodeValue(dst reflect.Value, raw interface{}) error {
+	if raw == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	// decode types implementing Scanner (or registered custom types)
+	if ok, err := v.scanValue(dst, raw); ok {
+		return err
+	}
+
+	src := reflect.ValueOf(raw)
+
+	// decode registered types held by interfaces
+	if dst.Kind() == reflect.Interface {
+		if data, ok := raw.(map[string]interface{}); ok {
+			if key, ok := data[DiscriminatorField].(string); ok {
+				return v.decodeConcrete(dst, key, data)
+			}
+		}
+	}
+
+	// handles values of the same type (e.g. strings, times, or document refs)
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+
+	// handle Firestore's special types (e.g. vectors)
+	if v.decodeSpecial(dst, src) {
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(dst.Type().Elem())
+		if err := v.decodeValue(elem.Elem(), raw); err != nil {
+			return err
+		}
+
+		dst.Set(elem)
+	case reflect.Struct:
+		data, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("firevault: cannot decode %s into %s", src.Type(), dst.Type())
+		}
+
+		return v.decodeStruct(dst, data)
+	case reflect.Slice, reflect.Array:
+		items, ok := raw.([]interface{})
+		if !ok {
+			return fmt.Errorf("firevault: cannot decode %s into %s", src.Type(), dst.Type())
+		}
+
+		if dst.Kind() == reflect.Slice {
+			dst.Set(reflect.MakeSlice(dst.Type(), len(items), len(items)))
+		}
+
+		for i := 0; i < len(items) && i < dst.Len(); i++ {
+			if err := v.decodeValue(dst.Index(i), items[i]); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		data, ok := raw.(map[string]interface{})
+		if !ok || dst.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("firevault: cannot decode %s into %s", src.Type(), dst.Type())
+		}
+
+		newMap := reflect.MakeMapWithSize(dst.Type(), len(data))
+
+		for key, item := range data {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := v.decodeValue(elem, item); err != nil {
+				return err
+			}
+
+			newMap.SetMapIndex(reflect.ValueOf(key).Convert(dst.Type().Key()), elem)
+		}
+
+		dst.Set(newMap)
+	default:
+		return v.decodeBasic(dst, src)
+	}
+
+	return nil
+}
+
+// decode a basic value (i.e. bool, number or string) into a Go value of a different type
+func (v *validator) decodeBasic(dst reflect.Value, src reflect.Value) error {
+	switch {
+	case src.CanInt() && dst.CanInt() && !dst.OverflowInt(src.Int()):
+		dst.SetInt(src.Int())
+	case src.CanInt() && dst.CanUint() && src.Int() >= 0 && !dst.OverflowUint(uint64(src.Int())):
+		dst.SetUint(uint64(src.Int()))
+	case src.CanInt() && dst.CanFloat():
+		dst.SetFloat(float64(src.Int()))
+	case src.CanFloat() && dst.CanFloat() && !dst.OverflowFloat(src.Float()):
+		dst.SetFloat(src.Float())
+	case src.Kind() == reflect.String && dst.Kind() == reflect.String:
+		dst.SetString(src.String())
+	case src.Kind() == reflect.Bool && dst.Kind() == reflect.Bool:
+		dst.SetBool(src.Bool())
+	default:
+		return fmt.Errorf("firevault: cannot decode %s into %s", src.Type(), dst.Type())
+	}
+
+	return nil
+}
+
+// get a (possibly promoted) field's value, allocating nil embedded pointers
+func (v *validator) allocFieldByIndex(strct reflect.Value, index []int) reflect.Value {
+	val := strct
+
+	for i, idx := range index {
+		if i > 0 && val.Kind() == reflect.Pointer {
+			if val.IsNil() {
+				val.Set(reflect.New(val.Type().Elem()))
+			}
+
+			val = val.Elem()
+		}
+
+		val = val.Field(idx)
+	}
+
+	return val
+}
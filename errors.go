@@ -0,0 +1,117 @@
+package firevault
+
+import (
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Errors returned by Firestore are wrapped with the
+// matching error below (keeping the original error,
+// and its status code), so they can be checked using
+// errors.Is.
+var (
+	// ErrNotFound is returned when a document that
+	// must exist (e.g. one being updated by ID) does
+	// not, or when FindOne finds no documents.
+	ErrNotFound = errors.New("firevault: document not found")
+	// ErrAlreadyExists is returned when creating a
+	// document with the ID of an existing one.
+	ErrAlreadyExists = errors.New("firevault: document already exists")
+	// ErrPreconditionFailed is returned when a
+	// document doesn't meet a precondition (e.g. its
+	// last update time doesn't match).
+	ErrPreconditionFailed = errors.New("firevault: precondition failed")
+	// ErrAborted is returned when a transaction is
+	// aborted (e.g. due to contention with other
+	// transactions).
+	ErrAborted = errors.New("firevault: operation aborted")
+	// ErrInvalidArgument is returned when Firestore
+	// rejects a request's data or query.
+	ErrInvalidArgument = errors.New("firevault: invalid argument")
+	// ErrIndexRequired is returned when a query
+	// requires a composite index which doesn't exist.
+	// The wrapped error's message contains a link to
+	// create it.
+	ErrIndexRequired = errors.New("firevault: query requires an index")
+)
+
+// DocError is returned when an operation on a single
+// document fails, holding the document's ID along
+// with the error.
+//
+// It can be retrieved using errors.As, including from
+// the joined errors of operations on multiple
+// documents.
+type DocError struct {
+	// The ID of the document (or its path, for
+	// documents of collection groups and cascaded
+	// subcollections).
+	DocID string
+	// The error which occurred.
+	Err error
+}
+
+// Error returns the error message, along with the
+// document's ID.
+func (e *DocError) Error() string {
+	return e.Err.Error() + " (docID: " + e.DocID + ")"
+}
+
+// Unwrap returns the contained error, allowing
+// the use of errors.Is and errors.As.
+func (e *DocError) Unwrap() error {
+	return e.Err
+}
+
+// an error returned by Firestore, along with its matching sentinel error
+type firestoreError struct {
+	err      error
+	sentinel error
+}
+
+func (e *firestoreError) Error() string {
+	return e.err.Error()
+}
+
+func (e *firestoreError) Unwrap() []error {
+	return []error{e.sentinel, e.err}
+}
+
+// wrap an error returned by Firestore with its matching sentinel error (if any)
+func wrapFirestoreError(err error) error {
+	var fsErr *firestoreError
+	if err == nil || errors.As(err, &fsErr) {
+		return err
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	var sentinel error
+
+	switch st.Code() {
+	case codes.NotFound:
+		sentinel = ErrNotFound
+	case codes.AlreadyExists:
+		sentinel = ErrAlreadyExists
+	case codes.FailedPrecondition:
+		sentinel = ErrPreconditionFailed
+
+		// queries without a required index fail with a link to create it
+		if indexURLRegex().MatchString(st.Message()) {
+			sentinel = ErrIndexRequired
+		}
+	case codes.Aborted:
+		sentinel = ErrAborted
+	case codes.InvalidArgument:
+		sentinel = ErrInvalidArgument
+	default:
+		return err
+	}
+
+	return &firestoreError{err, sentinel}
+}
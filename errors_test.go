@@ -0,0 +1,71 @@
+package firevault
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrors(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want error
+	}{
+		"not found":           {status.Error(codes.NotFound, "no document to update"), ErrNotFound},
+		"already exists":      {status.Error(codes.AlreadyExists, "document already exists"), ErrAlreadyExists},
+		"precondition failed": {status.Error(codes.FailedPrecondition, "update time mismatch"), ErrPreconditionFailed},
+		"index required": {
+			status.Error(
+				codes.FailedPrecondition,
+				"The query requires an index. You can create it here: "+
+					"https://console.firebase.google.com/v1/r/project/p/firestore/indexes?create_composite=Ckt",
+			),
+			ErrIndexRequired,
+		},
+		"index mentioned":  {status.Error(codes.FailedPrecondition, "index entry too large"), ErrPreconditionFailed},
+		"aborted":          {status.Error(codes.Aborted, "too much contention"), ErrAborted},
+		"invalid argument": {status.Error(codes.InvalidArgument, "invalid value"), ErrInvalidArgument},
+	}
+
+	for name, test := range tests {
+		// errors of multiple documents are joined together
+		err := errors.Join(&DocError{"other", errors.New("other")}, &DocError{"doc1", wrapFirestoreError(test.err)})
+
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s: expected error to match %v, got %v", name, test.want, err)
+		}
+
+		var docErr *DocError
+		if !errors.As(err, &docErr) || docErr.DocID != "other" {
+			t.Errorf("%s: expected first DocError to be retrieved, got %v", name, docErr)
+		}
+
+		// the original status is preserved
+		if status.Code(wrapFirestoreError(test.err)) != status.Code(test.err) {
+			t.Errorf("%s: expected status code %v to be preserved", name, status.Code(test.err))
+		}
+	}
+
+	wrapped := wrapFirestoreError(status.Error(codes.NotFound, "missing"))
+	if wrapFirestoreError(wrapped) != wrapped {
+		t.Errorf("Expected wrapped errors to be returned as they are")
+	}
+
+	for _, err := range []error{nil, errors.New("firevault: nil CollectionRef"), status.Error(codes.Unavailable, "")} {
+		if wrapFirestoreError(err) != err {
+			t.Errorf("Expected error without a sentinel to be returned as it is, got %v", wrapFirestoreError(err))
+		}
+	}
+
+	docErr := &DocError{"6QVHL46WCE680ZG2Xn3X", wrapped}
+	if got, want := docErr.Error(), "rpc error: code = NotFound desc = missing (docID: 6QVHL46WCE680ZG2Xn3X)"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	if pd := NewProblemDetails(docErr, 0); pd.Status != http.StatusNotFound {
+		t.Errorf("Expected status %d for ErrNotFound, got %d", http.StatusNotFound, pd.Status)
+	}
+}
@@ -0,0 +1,16 @@
+package firevault
+
+import "context"
+
+// exposes internals to external test packages
+
+func NewTestConnection() *Connection {
+	return &Connection{Validator: NewValidator()}
+}
+
+func ValidateMap[T interface{}](c *Connection, method string, data *T, opts ...Options) (map[string]interface{}, error) {
+	collection := &CollectionRef[T]{connection: c, path: "test"}
+	valOpts, _, _, _, _ := collection.parseOptions(methodType(method), opts...)
+
+	return c.validator.validate(context.Background(), data, valOpts)
+}
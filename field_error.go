@@ -1,8 +1,10 @@
 package firevault
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 // fieldError contains a single field's validation
@@ -16,11 +18,16 @@ type fieldError struct {
 	displayField string
 	path         string
 	structPath   string
+	jsonField    string
+	jsonPath     string
+	jsonPointer  string
 	value        reflect.Value
 	kind         reflect.Kind
 	typ          reflect.Type
 	rule         string
 	param        string
+	prev         reflect.Value
+	message      string // set by error formatters
 }
 
 // FieldError interface gives access to all field
@@ -70,6 +77,47 @@ type FieldError interface {
 	Error() string
 }
 
+// TransitionFieldError interface gives access to
+// a field's previously stored value, which aids in
+// describing failed transitions.
+//
+// All FieldError instances returned by Firevault
+// comply with it.
+type TransitionFieldError interface {
+	FieldError
+	// Previous returns the field's reflected value,
+	// as stored in Firestore before the update
+	// (e.g. during a failed transition). Returns an
+	// invalid reflect Value, if not available.
+	Previous() reflect.Value
+}
+
+// JSONFieldError interface gives access to a
+// field's json tag names, which aid in reporting
+// validation errors to HTTP API clients.
+//
+// All FieldError instances returned by Firevault
+// comply with it.
+type JSONFieldError interface {
+	FieldError
+	// JSONField returns the field's name, as
+	// defined by its json tag, falling back to
+	// the field's struct name.
+	JSONField() string
+	// JSONPath returns the field's dot-separated
+	// path, using the json tag names
+	// (e.g. "names.first").
+	JSONPath() string
+	// JSONPointer returns the field's path as
+	// an RFC 6901 JSON Pointer, using the json
+	// tag names (e.g. "/names/first").
+	JSONPointer() string
+	// MarshalJSON returns the JSON encoding
+	// of the error, using the json tag names
+	// of the fields.
+	MarshalJSON() ([]byte, error)
+}
+
 // Collection returns the path of the
 // collection that contains the document
 // modeled by the top-level struct.
@@ -114,6 +162,27 @@ func (fe *fieldError) StructPath() string {
 	return fe.structPath
 }
 
+// JSONField returns the field's name, as
+// defined by its json tag, falling back to
+// the field's struct name.
+func (fe *fieldError) JSONField() string {
+	return fe.jsonField
+}
+
+// JSONPath returns the field's dot-separated
+// path, using the json tag names
+// (e.g. "names.first").
+func (fe *fieldError) JSONPath() string {
+	return fe.jsonPath
+}
+
+// JSONPointer returns the field's path as
+// an RFC 6901 JSON Pointer, using the json
+// tag names (e.g. "/names/first").
+func (fe *fieldError) JSONPointer() string {
+	return fe.jsonPointer
+}
+
 // Value returns the field's reflect Value.
 func (fe *fieldError) Value() reflect.Value {
 	return fe.value
@@ -142,7 +211,118 @@ func (fe *fieldError) Param() string {
 	return fe.param
 }
 
+// Previous returns the field's reflected value,
+// as stored in Firestore before the update
+// (e.g. during a failed transition). Returns an
+// invalid reflect Value, if not available.
+func (fe *fieldError) Previous() reflect.Value {
+	return fe.prev
+}
+
 // Error returns the error message.
 func (fe *fieldError) Error() string {
 	return fmt.Sprintf("firevault: field validation for '%s' failed on the '%s' rule", fe.field, fe.rule)
 }
+
+// MarshalJSON returns the JSON encoding
+// of the error, using the json tag names
+// of the fields.
+func (fe *fieldError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newFieldErrorJSON(fe))
+}
+
+// FieldErrors is a collection of FieldError
+// instances, which can be returned or
+// marshalled as a single error.
+type FieldErrors []FieldError
+
+// Error returns the combined error messages.
+func (fe FieldErrors) Error() string {
+	msgs := make([]string, 0, len(fe))
+
+	for _, err := range fe {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the contained errors, allowing
+// the use of errors.Is and errors.As.
+func (fe FieldErrors) Unwrap() []error {
+	errs := make([]error, 0, len(fe))
+
+	for _, err := range fe {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// MarshalJSON returns the JSON encoding
+// of the errors, as an array.
+func (fe FieldErrors) MarshalJSON() ([]byte, error) {
+	errs := make([]fieldErrorJSON, 0, len(fe))
+
+	for _, err := range fe {
+		errs = append(errs, newFieldErrorJSON(err))
+	}
+
+	return json.Marshal(errs)
+}
+
+// serializable form of a FieldError
+type fieldErrorJSON struct {
+	Path    string `json:"path"`
+	Pointer string `json:"pointer"`
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// create serializable form of a FieldError, without exposing Firevault names
+func newFieldErrorJSON(fe FieldError) fieldErrorJSON {
+	feJSON := fieldErrorJSON{
+		Path:    fe.StructPath(),
+		Pointer: structPointer(fe.StructPath()),
+		Field:   fe.StructField(),
+		Rule:    fe.Rule(),
+		Param:   fe.Param(),
+	}
+
+	// other implementations of FieldError only expose struct names
+	if jsonFe, ok := fe.(JSONFieldError); ok {
+		feJSON.Path = jsonFe.JSONPath()
+		feJSON.Pointer = jsonFe.JSONPointer()
+		feJSON.Field = jsonFe.JSONField()
+	}
+
+	feJSON.Message = fmt.Sprintf("field validation for '%s' failed on the '%s' rule", feJSON.Field, feJSON.Rule)
+
+	// use the message of a registered error formatter, without the wrapped Firevault message
+	if fieldErr, ok := fe.(*fieldError); ok && fieldErr.message != "" {
+		feJSON.Message = strings.Replace(fieldErr.message, fieldErr.Error(), feJSON.Message, 1)
+	}
+
+	return feJSON
+}
+
+// get RFC 6901 JSON Pointer of a dot-separated struct path (e.g. "Names.First" or "Tags[1]")
+func structPointer(path string) string {
+	if path == "" {
+		return ""
+	}
+
+	var pointer strings.Builder
+
+	for _, segment := range strings.Split(strings.ReplaceAll(path, "[", ".["), ".") {
+		segment = strings.TrimSuffix(strings.TrimPrefix(segment, "["), "]")
+		segment = strings.ReplaceAll(segment, "~", "~0")
+		segment = strings.ReplaceAll(segment, "/", "~1")
+
+		pointer.WriteString("/" + segment)
+	}
+
+	return pointer.String()
+}
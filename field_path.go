@@ -0,0 +1,129 @@
+package firevault
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+)
+
+// resolve a dot-separated path of Firestore field names (or of Go struct field names, if byStruct)
+// into its stored path, also returning the type of the last field (nil if it can't be known)
+func (v *validator) resolveFieldPath(typ reflect.Type, path string, byStruct bool) (string, reflect.Type, error) {
+	if path == firestore.DocumentID {
+		return path, nil, nil
+	}
+
+	if path == "" {
+		return "", nil, errors.New("firevault: field path cannot be empty")
+	}
+
+	typ = derefType(typ)
+	segments := strings.Split(path, ".")
+	resolved := make([]string, 0, len(segments))
+
+	for len(segments) > 0 {
+		// any key of a map (or field of an interface's value) can be used
+		if typ.Kind() == reflect.Map || typ.Kind() == reflect.Interface || v.isValuer(typ) {
+			return strings.Join(append(resolved, segments...), "."), nil, nil
+		}
+
+		if typ.Kind() != reflect.Struct || v.isSpecialType(typ) {
+			return "", nil, errors.New(
+				"firevault: field path " + path + " is invalid, as " + typ.String() + " has no fields",
+			)
+		}
+
+		sd, ok := v.cache.get(typ)
+		if !ok {
+			var err error
+			sd, err = v.extractStructData(&fieldScope{typ: typ, value: reflect.New(typ).Elem()})
+			if err != nil {
+				return "", nil, err
+			}
+		}
+
+		field, consumed := v.matchPathField(typ, sd, segments, byStruct)
+		if field == nil {
+			return "", nil, errors.New("firevault: field path " + path + " does not exist on " + typ.String())
+		}
+
+		resolved = append(resolved, field.field)
+		segments = segments[consumed:]
+		typ = derefType(field.typ)
+	}
+
+	return strings.Join(resolved, "."), typ, nil
+}
+
+// find the field matching the first path segments, returning it and the number of segments used
+func (v *validator) matchPathField(
+	typ reflect.Type,
+	sd *structData,
+	segments []string,
+	byStruct bool,
+) (*fieldScope, int) {
+	for _, fs := range sd.fields {
+		if fs == nil {
+			continue
+		}
+
+		if !byStruct {
+			if fs.field == segments[0] {
+				return fs, 1
+			}
+
+			continue
+		}
+
+		// fields of non-embedded inlined structs are referred to through them
+		structPath := v.relativeStructPath(typ, fs.index)
+		if len(structPath) <= len(segments) && slices.Equal(structPath, segments[:len(structPath)]) {
+			return fs, len(structPath)
+		}
+	}
+
+	return nil, 0
+}
+
+// get the Go field names used to access a (possibly inlined) field of a struct
+func (v *validator) relativeStructPath(typ reflect.Type, index []int) []string {
+	names := make([]string, 0, len(index))
+
+	for _, i := range index {
+		field := derefType(typ).Field(i)
+
+		// fields of embedded structs are promoted
+		if !field.Anonymous {
+			names = append(names, field.Name)
+		}
+
+		typ = field.Type
+	}
+
+	return names
+}
+
+// check if an operator is valid for a field of the provided type (nil if unknown)
+func (v *validator) checkOperator(path string, operator Operator, typ reflect.Type) error {
+	switch operator {
+	case Equal, NotEqual, LessThan, LessThanOrEqual, GreaterThan, GreaterThanOrEqual, In, NotIn:
+		return nil
+	case ArrayContains, ArrayContainsAny:
+		if typ == nil || typ.Kind() == reflect.Interface || v.isValuer(typ) {
+			return nil
+		}
+
+		if (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) && !v.isSpecialType(typ) {
+			return nil
+		}
+
+		return errors.New(
+			"firevault: operator " + string(operator) + " cannot be used on field " + path + " of type " + typ.String(),
+		)
+	}
+
+	return errors.New("firevault: invalid operator " + string(operator) + " used on field " + path)
+}
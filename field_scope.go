@@ -13,17 +13,26 @@ type fieldScope struct {
 	displayField string
 	path         string
 	structPath   string
+	jsonField    string
+	jsonPath     string
+	jsonPointer  string
 	value        reflect.Value
 	kind         reflect.Kind
 	typ          reflect.Type
 	rule         string
 	param        string
+	parent       *fieldScope
+	opts         *validationOpts
+	prev         reflect.Value
 	// used for caching
-	pointer   bool
-	dive      bool
-	dynamic   bool
-	omitEmpty methodType
-	rules     []*ruleData
+	index      []int
+	pointer    bool
+	dive       bool
+	dynamic    bool
+	omitEmpty  methodType
+	immutable  bool
+	createOnly bool
+	rules      []*ruleData
 }
 
 // ruleData contains the information
@@ -35,6 +44,7 @@ type ruleData struct {
 	param       string
 	isTransform bool
 	runOnNil    bool
+	marshalled  bool
 	methodOnly  methodType
 }
 
@@ -84,6 +94,37 @@ type FieldScope interface {
 	// Param returns the param value, in string form
 	// for comparison.
 	Param() string
+	// Method returns the name of the method the
+	// validation is run for ("create", "update"
+	// or "validate").
+	Method() string
+	// DocumentID returns the ID of the document
+	// being written, if known (e.g. the custom ID
+	// during Create, or the single ID in an Update
+	// Query). Returns an empty string otherwise.
+	DocumentID() string
+	// DocumentIDs returns the IDs of all documents
+	// being written, if known (e.g. the IDs in an
+	// Update Query).
+	DocumentIDs() []string
+	// Root returns the reflected top-level struct
+	// being validated.
+	Root() reflect.Value
+	// Parent returns the scope of the field's parent
+	// (i.e. a nested struct, or a dived slice/map),
+	// allowing to walk up the hierarchy. Returns nil
+	// for the top-level struct.
+	Parent() FieldScope
+	// Options returns the Options passed to the
+	// calling method.
+	Options() Options
+	// Previous returns the field's reflected value,
+	// as currently stored in Firestore. Only
+	// available during an Update with the
+	// LoadPrevious option. Returns an invalid
+	// reflect Value otherwise, or if the field
+	// was not previously set.
+	Previous() reflect.Value
 }
 
 // Collection returns the path of the
@@ -164,3 +205,80 @@ func (fs *fieldScope) Rule() string {
 func (fs *fieldScope) Param() string {
 	return fs.param
 }
+
+// Method returns the name of the method the
+// validation is run for ("create", "update"
+// or "validate").
+func (fs *fieldScope) Method() string {
+	if fs.opts == nil {
+		return ""
+	}
+
+	return string(fs.opts.method)
+}
+
+// DocumentID returns the ID of the document
+// being written, if known (e.g. the custom ID
+// during Create, or the single ID in an Update
+// Query). Returns an empty string otherwise.
+func (fs *fieldScope) DocumentID() string {
+	if fs.opts == nil || len(fs.opts.docIDs) != 1 {
+		return ""
+	}
+
+	return fs.opts.docIDs[0]
+}
+
+// DocumentIDs returns the IDs of all documents
+// being written, if known (e.g. the IDs in an
+// Update Query).
+func (fs *fieldScope) DocumentIDs() []string {
+	if fs.opts == nil {
+		return nil
+	}
+
+	return fs.opts.docIDs
+}
+
+// Root returns the reflected top-level struct
+// being validated.
+func (fs *fieldScope) Root() reflect.Value {
+	if fs.opts == nil {
+		return reflect.Value{}
+	}
+
+	return fs.opts.root
+}
+
+// Parent returns the scope of the field's parent
+// (i.e. a nested struct, or a dived slice/map),
+// allowing to walk up the hierarchy. Returns nil
+// for the top-level struct.
+func (fs *fieldScope) Parent() FieldScope {
+	// the top-level struct's scope isn't exposed
+	if fs.parent == nil || fs.parent.path == "" {
+		return nil
+	}
+
+	return fs.parent
+}
+
+// Options returns the Options passed to the
+// calling method.
+func (fs *fieldScope) Options() Options {
+	if fs.opts == nil {
+		return Options{}
+	}
+
+	return fs.opts.options
+}
+
+// Previous returns the field's reflected value,
+// as currently stored in Firestore. Only
+// available during an Update with the
+// LoadPrevious option. Returns an invalid
+// reflect Value otherwise, or if the field
+// was not previously set.
+func (fs *fieldScope) Previous() reflect.Value {
+	return fs.prev
+}
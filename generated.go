@@ -0,0 +1,343 @@
+package firevault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// GeneratedFunc is the signature of the validate
+// and marshal functions produced by the
+// firevault-gen tool.
+//
+// It validates the provided data and returns the
+// same map the reflection-based validator would
+// produce.
+type GeneratedFunc[T interface{}] func(s *GeneratedScope, data *T) (map[string]interface{}, error)
+
+// the function that's executed for a registered type
+type generatedFuncInternal func(s *GeneratedScope, data interface{}) (map[string]interface{}, error)
+
+// GeneratedField describes a struct field, as
+// parsed by the firevault-gen tool.
+//
+// It's used by generated code and is not
+// intended to be created manually.
+type GeneratedField struct {
+	// Name is the field's Firestore name.
+	Name string
+	// StructName is the field's Go name.
+	StructName string
+	// JSONName is the field's json tag name.
+	JSONName string
+	// Tag is the field's full firevault tag.
+	Tag string
+	// Pointer reports whether the field is a pointer.
+	Pointer bool
+}
+
+// GeneratedScope holds the validation state of a
+// struct (or dived element) during a call to a
+// generated function.
+//
+// It's used by generated code and is not
+// intended to be created manually.
+type GeneratedScope struct {
+	ctx  context.Context
+	v    *validator
+	opts *validationOpts
+	fs   *fieldScope
+	data interface{}
+}
+
+// Register a generated validate and marshal
+// function for a struct type.
+//
+// Once registered, the function is used
+// automatically, instead of reflection, whenever
+// data of the same type is validated through the
+// Validator (or the Connection embedding it).
+// Custom validations and transformations are
+// still called by name, through the Validator's
+// registry. Fields with rules are still
+// reflected when their rules are applied, as
+// rules receive a FieldScope.
+//
+// Validation falls back to reflection when stored
+// documents are needed (e.g. when using the
+// LoadPrevious option).
+//
+// Registering such functions is not thread-safe;
+// it is intended that all functions be registered,
+// prior to any validation.
+func RegisterGenerated[T interface{}](validator *Validator, fn GeneratedFunc[T]) error {
+	if validator == nil {
+		return errors.New("firevault: nil Validator")
+	}
+
+	if fn == nil {
+		return errors.New("firevault: generated function cannot be empty")
+	}
+
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return errors.New("firevault: generated function type must be a struct")
+	}
+
+	validator.validator.generated[typ] = func(s *GeneratedScope, data interface{}) (map[string]interface{}, error) {
+		return fn(s, data.(*T))
+	}
+
+	return nil
+}
+
+// Enter sets the struct being validated by the
+// current scope.
+func (s *GeneratedScope) Enter(data interface{}) *GeneratedScope {
+	s.data = data
+	return s
+}
+
+// Child returns the scope of a nested struct field.
+func (s *GeneratedScope) Child(f *GeneratedField) *GeneratedScope {
+	return &GeneratedScope{s.ctx, s.v, s.opts, s.childFieldScope(f), nil}
+}
+
+// Index returns the scope of a dived slice element.
+func (s *GeneratedScope) Index(i int) *GeneratedScope {
+	fs := &fieldScope{
+		collPath:    s.fs.collPath,
+		strct:       s.fs.strct,
+		field:       fmt.Sprintf("[%d]", i),
+		structField: fmt.Sprintf("[%d]", i),
+		path:        fmt.Sprintf("%s[%d]", s.fs.path, i),
+		structPath:  fmt.Sprintf("%s[%d]", s.fs.structPath, i),
+		jsonField:   fmt.Sprintf("[%d]", i),
+		jsonPath:    fmt.Sprintf("%s[%d]", s.fs.jsonPath, i),
+		jsonPointer: fmt.Sprintf("%s/%d", s.fs.jsonPointer, i),
+		parent:      s.fs,
+		opts:        s.opts,
+		dynamic:     true,
+	}
+
+	return &GeneratedScope{s.ctx, s.v, s.opts, fs, nil}
+}
+
+// Key returns the scope of a dived map element.
+func (s *GeneratedScope) Key(key string) *GeneratedScope {
+	fs := &fieldScope{
+		collPath:    s.fs.collPath,
+		strct:       s.fs.strct,
+		field:       key,
+		structField: key,
+		path:        s.fs.path + "." + key,
+		structPath:  s.fs.structPath + "." + key,
+		jsonField:   key,
+		jsonPath:    s.fs.jsonPath + "." + key,
+		jsonPointer: s.v.getJSONPointer(s.fs.jsonPointer, key),
+		parent:      s.fs,
+		opts:        s.opts,
+		dynamic:     true,
+	}
+
+	return &GeneratedScope{s.ctx, s.v, s.opts, fs, nil}
+}
+
+// Value validates a value held by an interface,
+// returning the map of its registered concrete
+// type (including the DiscriminatorField), or
+// the value itself if its type isn't registered.
+func (s *GeneratedScope) Value(value interface{}) (interface{}, error) {
+	if value == nil || len(s.v.types) == 0 {
+		return value, nil
+	}
+
+	// interface values must be addressable, so they're reflected via a pointer
+	s.fs.value = reflect.ValueOf(&value).Elem()
+	s.fs.typ = s.fs.value.Type()
+	s.fs.kind = reflect.Interface
+
+	return s.v.processInterfaceValue(s.ctx, s.fs, *s.opts)
+}
+
+// Keep reports whether a field should be added to
+// the data map, based on its "omitempty",
+// "immutable" and "createonly" rules.
+//
+// If the field should be deleted (e.g. when using
+// the ReplaceAll option), it's added to the data
+// map as Delete and false is returned.
+func (s *GeneratedScope) Keep(
+	f *GeneratedField,
+	data map[string]interface{},
+	hasValue bool,
+	ptr interface{},
+) (bool, error) {
+	tmpl := s.v.generatedField(f)
+	path := s.v.getFieldPath(s.fs.path, f.Name)
+
+	// strip (or reject) fields which can't be written during an update
+	if s.opts.method == update && (tmpl.immutable || tmpl.createOnly) {
+		if !hasValue || !s.opts.rejectImmutable {
+			return false, nil
+		}
+
+		fs := s.newFieldScope(f, tmpl, ptr)
+
+		fs.rule = "immutable"
+		if tmpl.createOnly {
+			fs.rule = "createonly"
+		}
+
+		return false, s.v.generateFieldErr(fs)
+	}
+
+	// skip empty field with omitempty tags
+	shouldOmit := tmpl.omitEmpty == all || tmpl.omitEmpty == s.opts.method
+	if shouldOmit && !slices.Contains(s.opts.emptyFieldsAllowed, path) && !hasValue {
+		// true if merging has been disabled (during an update)
+		if s.opts.deleteEmpty {
+			data[f.Name] = Delete
+		}
+
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// HasValue reports whether the value pointed to
+// is not the zero value of its type. Used by
+// generated code for types which can't be
+// compared directly.
+func (s *GeneratedScope) HasValue(ptr interface{}) bool {
+	val := reflect.ValueOf(ptr).Elem()
+	return hasValue(val.Kind(), val)
+}
+
+// ApplyGeneratedRules applies the validations and
+// transformations of a field, returning its
+// (possibly transformed) dereferenced value and
+// whether it's set.
+//
+// ptr must point to the struct field, while x and
+// ok hold the field's current dereferenced value
+// and whether it's set (i.e. not a nil pointer).
+//
+// The field is reflected (only) when it has
+// rules, so they can be passed a FieldScope.
+//
+// It's used by generated code and is not
+// intended to be called manually.
+func ApplyGeneratedRules[X interface{}](
+	s *GeneratedScope,
+	f *GeneratedField,
+	ptr interface{},
+	x X,
+	ok bool,
+) (X, bool, error) {
+	tmpl := s.v.generatedField(f)
+	if len(tmpl.rules) == 0 {
+		return x, ok, nil
+	}
+
+	path := s.v.getFieldPath(s.fs.path, f.Name)
+
+	// check if validation should be skipped for this field
+	skipVal := s.opts.skipValidation &&
+		(len(s.opts.skipValFields) == 0 || slices.Contains(s.opts.skipValFields, path)) &&
+		len(s.opts.skipValRules) == 0
+	if skipVal {
+		return x, ok, nil
+	}
+
+	fs := s.newFieldScope(f, tmpl, ptr)
+	fieldValue := fs.value
+
+	err := s.v.applyRules(s.ctx, fs, *s.opts)
+	if err != nil {
+		return x, ok, err
+	}
+
+	// set original struct's field value if changed
+	if s.opts.modifyOriginal && fieldValue != fs.value {
+		fieldValue.Set(fs.value)
+	}
+
+	if !fs.value.IsValid() {
+		var zero X
+		return zero, false, nil
+	}
+
+	newX, isX := fs.value.Interface().(X)
+	if !isX {
+		return x, ok, nil
+	}
+
+	return newX, true, nil
+}
+
+// create scope of a field (with its reflected value)
+func (s *GeneratedScope) newFieldScope(f *GeneratedField, tmpl *fieldScope, ptr interface{}) *fieldScope {
+	fs := s.childFieldScope(f)
+	fs.value = reflect.ValueOf(ptr).Elem()
+	fs.typ = fs.value.Type()
+	fs.kind = fs.typ.Kind()
+	fs.rules = tmpl.rules
+
+	if f.Pointer {
+		fs.pointer = true
+		fs.typ = fs.typ.Elem()
+		fs.kind = fs.typ.Kind()
+		fs.value = fs.value.Elem()
+	}
+
+	return fs
+}
+
+// create scope of a field (without its value)
+func (s *GeneratedScope) childFieldScope(f *GeneratedField) *fieldScope {
+	// reflect parent struct lazily, only when needed
+	if !s.fs.value.IsValid() && s.data != nil {
+		s.fs.value = reflect.ValueOf(s.data).Elem()
+		s.fs.typ = s.fs.value.Type()
+		s.fs.kind = s.fs.typ.Kind()
+	}
+
+	return &fieldScope{
+		collPath:     s.fs.collPath,
+		strct:        s.fs.value,
+		field:        f.Name,
+		structField:  f.StructName,
+		displayField: s.v.getDisplayName(f.StructName),
+		path:         s.v.getFieldPath(s.fs.path, f.Name),
+		structPath:   s.v.getFieldPath(s.fs.structPath, f.StructName),
+		jsonField:    f.JSONName,
+		jsonPath:     s.v.getFieldPath(s.fs.jsonPath, f.JSONName),
+		jsonPointer:  s.v.getJSONPointer(s.fs.jsonPointer, f.JSONName),
+		parent:       s.fs,
+		opts:         s.opts,
+	}
+}
+
+// get parsed tag data of a generated field (parsing it once)
+func (v *validator) generatedField(f *GeneratedField) *fieldScope {
+	if cached, ok := v.generatedFields.Load(f); ok {
+		return cached.(*fieldScope)
+	}
+
+	rules := v.parseTag(f.Tag)
+
+	fs := &fieldScope{
+		omitEmpty:  v.shouldSkipField(rules),
+		dive:       slices.Contains(rules, "dive"),
+		immutable:  slices.Contains(rules, "immutable"),
+		createOnly: slices.Contains(rules, "createonly"),
+		rules:      v.extractRuleData(v.cleanRules(rules)),
+	}
+
+	v.generatedFields.Store(f, fs)
+	return fs
+}
@@ -0,0 +1,151 @@
+package firevault_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	firevault "github.com/bobch27/firevault_go"
+	"github.com/bobch27/firevault_go/internal/gentest"
+)
+
+func TestGeneratedValidators(t *testing.T) {
+	newConnection := func(generated bool) *firevault.Connection {
+		connection := firevault.NewTestConnection()
+
+		err := connection.RegisterValidation("is_status", firevault.ValidationFunc(func(fs firevault.FieldScope) (bool, error) {
+			return fs.Value().String() == "active" || fs.Value().String() == "banned", nil
+		}))
+		if err != nil {
+			t.Fatalf("Failed to register validation: %v", err)
+		}
+
+		err = connection.RegisterType("circle", gentest.Circle{})
+		if err != nil {
+			t.Fatalf("Failed to register type: %v", err)
+		}
+
+		if generated {
+			err = gentest.RegisterFirevaultValidators(connection.Validator)
+			if err != nil {
+				t.Fatalf("Failed to register generated validators: %v", err)
+			}
+		}
+
+		return connection
+	}
+
+	reflective := newConnection(false)
+	generated := newConnection(true)
+
+	score := 9.5
+	newUser := func() *gentest.User {
+		return &gentest.User{
+			Name:      "  John Doe",
+			Email:     "john@example.com",
+			Age:       30,
+			Active:    true,
+			Status:    "active",
+			Score:     &score,
+			Owner:     "admin",
+			CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			Address:   gentest.Address{Street: " 1 Main St ", City: "Anytown"},
+			Previous:  []gentest.Address{{Street: " 2 Side St "}},
+			Others:    []*gentest.Address{{Street: "3 High St"}, nil},
+			Tags:      []string{"a", "b"},
+			Labels:    map[string]string{"team": "core"},
+			Places:    map[string]*gentest.Address{"home": {Street: "4 Low St"}, "work": nil},
+			Extra:     "extra",
+			Shape:     gentest.Circle{Radius: 2},
+			Shapes:    []gentest.Shape{gentest.Circle{Radius: 1}, nil},
+			Meta:      gentest.Meta{Source: "web"},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		user    func() *gentest.User
+		opts    []firevault.Options
+		wantErr bool
+	}{
+		{"Create", "create", newUser, nil, false},
+		{
+			"Partial update", "update",
+			func() *gentest.User { return &gentest.User{Name: "Jane", Address: gentest.Address{Street: "5 Elm St"}} },
+			nil,
+			false,
+		},
+		{
+			"Update replacing all", "update",
+			func() *gentest.User { return &gentest.User{Name: "Jane", Address: gentest.Address{Street: "5 Elm St"}} },
+			[]firevault.Options{firevault.NewOptions().ReplaceAll()},
+			false,
+		},
+		{
+			"Rejected immutable field", "update",
+			func() *gentest.User { return &gentest.User{Name: "Jane", Owner: "other"} },
+			[]firevault.Options{firevault.NewOptions().RejectImmutableFields()},
+			true,
+		},
+		{
+			"Custom validation failure", "create",
+			func() *gentest.User { u := newUser(); u.Status = "unknown"; return u },
+			nil,
+			true,
+		},
+		{
+			"Nested validation failure", "create",
+			func() *gentest.User { u := newUser(); u.Places["home"].Street = ""; return u },
+			nil,
+			true,
+		},
+		{
+			"Interface validation failure", "create",
+			func() *gentest.User { u := newUser(); u.Shapes[0] = gentest.Circle{Radius: 200}; return u },
+			nil,
+			true,
+		},
+		{
+			"Skipped validation", "create",
+			func() *gentest.User { return &gentest.User{} },
+			[]firevault.Options{firevault.NewOptions().SkipValidationFields()},
+			false,
+		},
+		{
+			"Modified original", "create",
+			newUser,
+			[]firevault.Options{firevault.NewOptions().ModifyOriginal()},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantUser, gotUser := tt.user(), tt.user()
+
+			want, wantErr := firevault.ValidateMap(reflective, tt.method, wantUser, tt.opts...)
+			got, gotErr := firevault.ValidateMap(generated, tt.method, gotUser, tt.opts...)
+
+			if (gotErr != nil) != tt.wantErr || (wantErr != nil) != tt.wantErr {
+				t.Fatalf("errors = %v (generated), %v (reflective), wantErr %v", gotErr, wantErr, tt.wantErr)
+			}
+
+			if gotErr != nil {
+				if gotErr.Error() != wantErr.Error() {
+					t.Errorf("error = %q, want %q", gotErr, wantErr)
+				}
+
+				return
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("generated map = %#v, want %#v", got, want)
+			}
+
+			if !reflect.DeepEqual(gotUser, wantUser) {
+				t.Errorf("generated struct = %+v, want %+v", gotUser, wantUser)
+			}
+		})
+	}
+}
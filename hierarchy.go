@@ -0,0 +1,301 @@
+package firevault
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+)
+
+// Create a new CollectionRef instance, referring to a
+// subcollection of a document in the parent collection.
+//
+// The docID argument is the ID of the parent document,
+// while the collectionID argument is the ID of the
+// subcollection (e.g. "comments"). Neither of them can
+// contain a slash.
+//
+// The subcollection's model (C) can differ from the
+// parent collection's model (P).
+//
+// Returns nil if the parent is nil (or refers to a
+// collection group), or if either ID is invalid.
+func SubCollection[C interface{}, P interface{}](
+	parent *CollectionRef[P],
+	docID string,
+	collectionID string,
+) *CollectionRef[C] {
+	if parent == nil || parent.group != nil {
+		return nil
+	}
+
+	if !isValidID(docID) || !isValidID(collectionID) {
+		return nil
+	}
+
+	return Collection[C](parent.connection, parent.path+"/"+docID+"/"+collectionID)
+}
+
+// Register a subcollection, which documents of the
+// parent collection can have.
+//
+// Both arguments are collection IDs (e.g. "posts" and
+// "comments"), rather than paths, so the relation
+// applies to collections with the same ID at any
+// depth (and to collection groups). A collection can be
+// registered as its own subcollection (e.g. nested
+// folders).
+//
+// Registered relations are used when deleting
+// documents with the Cascade option.
+//
+// Registering the same relation more than once has no
+// effect. Relations should be registered before the
+// Connection is used, as registration is not safe for
+// concurrent use.
+func (c *Connection) RegisterSubCollection(parentCollectionID string, childCollectionID string) error {
+	if c == nil {
+		return errors.New("firevault: nil Connection")
+	}
+
+	if !isValidID(parentCollectionID) || !isValidID(childCollectionID) {
+		return errors.New("firevault: collection IDs cannot be empty or contain a slash")
+	}
+
+	if c.subCollections == nil {
+		c.subCollections = make(map[string][]string)
+	}
+
+	if !slices.Contains(c.subCollections[parentCollectionID], childCollectionID) {
+		c.subCollections[parentCollectionID] = append(c.subCollections[parentCollectionID], childCollectionID)
+	}
+
+	return nil
+}
+
+// SubCollections returns the IDs of the subcollections
+// registered for a collection, in registration order.
+func (c *Connection) SubCollections(collectionID string) []string {
+	if c == nil {
+		return nil
+	}
+
+	return slices.Clone(c.subCollections[collectionID])
+}
+
+// ParentCollections returns the IDs of the collections
+// for which a collection is registered as a
+// subcollection, sorted.
+//
+// Collection groups use it to tell whether a fetched
+// document belongs to the registered hierarchy.
+func (c *Connection) ParentCollections(collectionID string) []string {
+	if c == nil {
+		return nil
+	}
+
+	var parents []string
+
+	for parentID, childIDs := range c.subCollections {
+		if slices.Contains(childIDs, collectionID) {
+			parents = append(parents, parentID)
+		}
+	}
+
+	slices.Sort(parents)
+	return parents
+}
+
+// delete matching documents, along with the documents of their registered subcollections
+// (outside a transaction, this isn't atomic, but descendants go first so a retry can finish it)
+func (c *CollectionRef[T]) cascadeDelete(
+	ctx context.Context,
+	tx *firestore.Transaction,
+	query Query,
+	precond firestore.Precondition,
+) error {
+	if tx != nil {
+		ctx = context.Background() // bg ctx since we're using tx
+	}
+
+	docIDs := query.ids
+
+	if len(docIDs) == 0 {
+		docs, err := c.fetchDocsByQuery(ctx, tx, query)
+		if err != nil {
+			return err
+		}
+
+		for _, doc := range docs {
+			docIDs = append(docIDs, c.docKey(doc))
+		}
+	}
+
+	if len(docIDs) == 0 {
+		return nil // no matching documents
+	}
+
+	refs := make([]*firestore.DocumentRef, len(docIDs))
+	for i, docID := range docIDs {
+		ref, err := c.doc(docID)
+		if err != nil {
+			return &DocError{docID, err}
+		}
+
+		refs[i] = ref
+	}
+
+	if tx != nil {
+		return c.cascadeDeleteInTransaction(tx, refs, docIDs, precond)
+	}
+
+	levels, err := c.connection.descendants(ctx, nil, refs)
+	if err != nil {
+		return err
+	}
+
+	bulkWriter := c.connection.client.BulkWriter(ctx)
+	defer bulkWriter.End()
+
+	// the deepest descendants are deleted first, so an interrupted cascade never leaves
+	// subcollections under a deleted document, and can be completed by a retry
+	for i := len(levels) - 1; i >= 0; i-- {
+		// descendants are identified by their path
+		keys := make([]string, len(levels[i]))
+		for j, ref := range levels[i] {
+			keys[j] = relativePath(ref)
+		}
+
+		if err := errors.Join(c.connection.bulkDelete(bulkWriter, levels[i], keys, nil)...); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(c.connection.bulkDelete(bulkWriter, refs, docIDs, precond)...)
+}
+
+// delete matching documents, along with the documents of their registered subcollections, within a transaction
+func (c *CollectionRef[T]) cascadeDeleteInTransaction(
+	tx *firestore.Transaction,
+	refs []*firestore.DocumentRef,
+	docIDs []string,
+	precond firestore.Precondition,
+) error {
+	// all reads must happen before any writes in a transaction
+	levels, err := c.connection.descendants(context.Background(), tx, refs)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	// preconditions only apply to matching documents (a failed one aborts the whole transaction)
+	for i, ref := range refs {
+		var preconds []firestore.Precondition
+		if precond != nil {
+			preconds = append(preconds, precond)
+		}
+
+		if err := tx.Delete(ref, preconds...); err != nil {
+			errs = append(errs, &DocError{docIDs[i], wrapFirestoreError(err)})
+		}
+	}
+
+	for _, ref := range slices.Concat(levels...) {
+		if err := tx.Delete(ref); err != nil {
+			errs = append(errs, &DocError{relativePath(ref), wrapFirestoreError(err)})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// delete documents using a bulk writer, returning the error of each document (nil if deleted)
+func (c *Connection) bulkDelete(
+	bulkWriter *firestore.BulkWriter,
+	refs []*firestore.DocumentRef,
+	keys []string,
+	precond firestore.Precondition,
+) []error {
+	errs := make([]error, len(refs))
+	jobs := make([]*firestore.BulkWriterJob, len(refs))
+
+	for i, ref := range refs {
+		var err error
+
+		if precond != nil {
+			jobs[i], err = bulkWriter.Delete(ref, precond)
+		} else {
+			jobs[i], err = bulkWriter.Delete(ref)
+		}
+
+		if err != nil {
+			errs[i] = &DocError{keys[i], wrapFirestoreError(err)}
+		}
+	}
+
+	// wait for all operations to complete
+	bulkWriter.Flush()
+
+	for i, job := range jobs {
+		if job == nil {
+			continue
+		}
+
+		if _, err := job.Results(); err != nil {
+			errs[i] = &DocError{keys[i], wrapFirestoreError(err)}
+		}
+	}
+
+	return errs
+}
+
+// find all documents in the registered subcollections of provided documents (recursively), by depth
+func (c *Connection) descendants(
+	ctx context.Context,
+	tx *firestore.Transaction,
+	refs []*firestore.DocumentRef,
+) ([][]*firestore.DocumentRef, error) {
+	var found [][]*firestore.DocumentRef
+
+	for len(refs) > 0 {
+		var next []*firestore.DocumentRef
+
+		for _, ref := range refs {
+			for _, childID := range c.subCollections[ref.Parent.ID] {
+				// only document references are needed
+				query := ref.Collection(childID).Select()
+
+				var iter *firestore.DocumentIterator
+				if tx != nil {
+					iter = tx.Documents(query)
+				} else {
+					iter = query.Documents(ctx)
+				}
+
+				docSnaps, err := iter.GetAll()
+				if err != nil {
+					return nil, wrapFirestoreError(err)
+				}
+
+				for _, docSnap := range docSnaps {
+					next = append(next, docSnap.Ref)
+				}
+			}
+		}
+
+		if len(next) > 0 {
+			found = append(found, next)
+		}
+		refs = next
+	}
+
+	return found, nil
+}
+
+// check if a document or collection ID can be used as a single path segment
+func isValidID(id string) bool {
+	return id != "" && !strings.Contains(id, "/")
+}
@@ -0,0 +1,123 @@
+package firevault
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/option"
+)
+
+func TestSubCollections(t *testing.T) {
+	paths := map[string]string{
+		"posts/p1":                   "",
+		"posts/p1/comments/c1":       "posts/p1",
+		"users/u1/posts/p1/likes/l1": "users/u1/posts/p1",
+	}
+
+	for path, want := range paths {
+		doc := Document[map[string]interface{}]{Path: path}
+		if got := doc.ParentPath(); got != want {
+			t.Errorf("ParentPath() of %s = %q, want %q", path, got, want)
+		}
+	}
+
+	// the client connects lazily, so no requests are made
+	client, err := firestore.NewClient(
+		context.Background(),
+		"test-project",
+		option.WithoutAuthentication(),
+		option.WithEndpoint("localhost:0"),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer client.Close()
+
+	connection := &Connection{Validator: NewValidator(), client: client}
+	users := Collection[map[string]interface{}](connection, "users")
+
+	posts := SubCollection[map[string]interface{}](users, "u1", "posts")
+	if posts == nil || posts.path != "users/u1/posts" {
+		t.Fatalf("Expected CollectionRef with path users/u1/posts, got %+v", posts)
+	}
+
+	likes := SubCollection[map[string]interface{}](posts, "p1", "likes")
+	if likes == nil || likes.ref.Parent.ID != "p1" || likes.ref.Parent.Parent.ID != "posts" {
+		t.Errorf("Expected subcollection of document p1 in posts")
+	}
+
+	invalid := map[string][2]string{
+		"empty document ID":    {"", "posts"},
+		"empty collection ID":  {"u1", ""},
+		"slash in document ID": {"u1/posts/p1", "likes"},
+		"slash in collection":  {"u1", "posts/p1/likes"},
+	}
+
+	for name, ids := range invalid {
+		if SubCollection[map[string]interface{}](users, ids[0], ids[1]) != nil {
+			t.Errorf("Expected nil CollectionRef for %s", name)
+		}
+	}
+
+	if SubCollection[map[string]interface{}, map[string]interface{}](nil, "u1", "posts") != nil {
+		t.Errorf("Expected nil CollectionRef for nil parent")
+	}
+
+	group := CollectionGroup[map[string]interface{}](connection, "posts")
+	if SubCollection[map[string]interface{}](group.collection, "p1", "likes") != nil {
+		t.Errorf("Expected nil CollectionRef for collection group parent")
+	}
+
+	// relations are registered once, in order
+	for _, child := range []string{"posts", "likes", "posts"} {
+		if err := connection.RegisterSubCollection("users", child); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	if got := connection.SubCollections("users"); !reflect.DeepEqual(got, []string{"posts", "likes"}) {
+		t.Errorf("SubCollections(users) = %v, want [posts likes]", got)
+	}
+
+	if err := connection.RegisterSubCollection("users/u1/posts", "likes"); err == nil {
+		t.Errorf("Expected error for collection path instead of ID")
+	}
+
+	if !NewOptions().Cascade().cascade {
+		t.Errorf("Expected Cascade to set cascade option")
+	}
+
+	// collection groups know the registered hierarchy
+	if err := connection.RegisterSubCollection("albums", "likes"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if got := connection.ParentCollections("likes"); !reflect.DeepEqual(got, []string{"albums", "users"}) {
+		t.Errorf("ParentCollections(likes) = %v, want [albums users]", got)
+	}
+
+	likesGroup := CollectionGroup[map[string]interface{}](connection, "likes")
+	hierarchy := map[string]bool{
+		"users/u1/likes/l1":          true,
+		"albums/a1/likes/l1":         true,
+		"users/u1/posts/p1/likes/l1": false,
+		"likes/l1":                   false,
+	}
+
+	for path, want := range hierarchy {
+		if got := likesGroup.InHierarchy(Document[map[string]interface{}]{Path: path}); got != want {
+			t.Errorf("InHierarchy() of %s = %v, want %v", path, got, want)
+		}
+	}
+
+	var nilConnection *Connection
+	if err := nilConnection.RegisterSubCollection("users", "posts"); err == nil {
+		t.Errorf("Expected error for nil Connection")
+	}
+
+	if nilConnection.SubCollections("users") != nil || nilConnection.ParentCollections("posts") != nil {
+		t.Errorf("Expected no relations for nil Connection")
+	}
+}
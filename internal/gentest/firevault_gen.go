@@ -0,0 +1,501 @@
+// Code generated by firevault-gen. DO NOT EDIT.
+
+package gentest
+
+import (
+	"time"
+
+	firevault "github.com/bobch27/firevault_go"
+)
+
+var firevaultFieldsAddress = [...]firevault.GeneratedField{
+	{Name: "street", StructName: "Street", JSONName: "street", Tag: "street,required,transform:trim_space", Pointer: false},
+	{Name: "city", StructName: "City", JSONName: "city", Tag: "city,omitempty", Pointer: false},
+}
+
+func firevaultValidateAddress(s *firevault.GeneratedScope, data *Address) (map[string]interface{}, error) {
+	s.Enter(data)
+	m := make(map[string]interface{}, 2)
+
+	// Street
+	if keep, err := s.Keep(&firevaultFieldsAddress[0], m, data.Street != "", &data.Street); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Street, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsAddress[0], &data.Street, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			m["street"] = x
+		}
+	}
+
+	// City
+	if keep, err := s.Keep(&firevaultFieldsAddress[1], m, data.City != "", &data.City); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.City, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsAddress[1], &data.City, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			m["city"] = x
+		}
+	}
+
+	return m, nil
+}
+
+var firevaultFieldsMeta = [...]firevault.GeneratedField{
+	{Name: "source", StructName: "Source", JSONName: "Source", Tag: "source", Pointer: false},
+}
+
+func firevaultValidateMeta(s *firevault.GeneratedScope, data *Meta) (map[string]interface{}, error) {
+	s.Enter(data)
+	m := make(map[string]interface{}, 1)
+
+	// Source
+	if keep, err := s.Keep(&firevaultFieldsMeta[0], m, data.Source != "", &data.Source); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Source, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsMeta[0], &data.Source, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			m["source"] = x
+		}
+	}
+
+	return m, nil
+}
+
+var firevaultFieldsUser = [...]firevault.GeneratedField{
+	{Name: "name", StructName: "Name", JSONName: "name", Tag: "name,required,min=3,transform:lowercase", Pointer: false},
+	{Name: "email", StructName: "Email", JSONName: "email", Tag: "email,required_create,email,omitempty_update", Pointer: false},
+	{Name: "age", StructName: "Age", JSONName: "age", Tag: "age,omitempty,max=150", Pointer: false},
+	{Name: "active", StructName: "Active", JSONName: "Active", Tag: "active", Pointer: false},
+	{Name: "status", StructName: "Status", JSONName: "Status", Tag: "status,omitempty,is_status", Pointer: false},
+	{Name: "score", StructName: "Score", JSONName: "Score", Tag: "score,omitempty", Pointer: true},
+	{Name: "owner", StructName: "Owner", JSONName: "owner", Tag: "owner,immutable", Pointer: false},
+	{Name: "created_at", StructName: "CreatedAt", JSONName: "createdAt", Tag: "created_at,omitempty", Pointer: false},
+	{Name: "address", StructName: "Address", JSONName: "address", Tag: "address", Pointer: false},
+	{Name: "billing", StructName: "Billing", JSONName: "billing", Tag: "billing,omitempty", Pointer: true},
+	{Name: "previous", StructName: "Previous", JSONName: "previous", Tag: "previous,dive", Pointer: false},
+	{Name: "others", StructName: "Others", JSONName: "Others", Tag: "others,omitempty,dive", Pointer: false},
+	{Name: "tags", StructName: "Tags", JSONName: "Tags", Tag: "tags,omitempty,max=3", Pointer: false},
+	{Name: "labels", StructName: "Labels", JSONName: "Labels", Tag: "labels,omitempty,dive", Pointer: false},
+	{Name: "places", StructName: "Places", JSONName: "Places", Tag: "places,omitempty,dive", Pointer: false},
+	{Name: "extra", StructName: "Extra", JSONName: "Extra", Tag: "extra,omitempty", Pointer: false},
+	{Name: "shape", StructName: "Shape", JSONName: "Shape", Tag: "shape,omitempty", Pointer: false},
+	{Name: "shapes", StructName: "Shapes", JSONName: "Shapes", Tag: "shapes,omitempty,dive", Pointer: false},
+	{Name: "meta", StructName: "Meta", JSONName: "Meta", Tag: "meta,omitempty", Pointer: false},
+}
+
+func firevaultValidateUser(s *firevault.GeneratedScope, data *User) (map[string]interface{}, error) {
+	s.Enter(data)
+	m := make(map[string]interface{}, 19)
+
+	// Name
+	if keep, err := s.Keep(&firevaultFieldsUser[0], m, data.Name != "", &data.Name); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Name, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[0], &data.Name, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			m["name"] = x
+		}
+	}
+
+	// Email
+	if keep, err := s.Keep(&firevaultFieldsUser[1], m, data.Email != "", &data.Email); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Email, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[1], &data.Email, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			m["email"] = x
+		}
+	}
+
+	// Age
+	if keep, err := s.Keep(&firevaultFieldsUser[2], m, data.Age != 0, &data.Age); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Age, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[2], &data.Age, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			m["age"] = x
+		}
+	}
+
+	// Active
+	if keep, err := s.Keep(&firevaultFieldsUser[3], m, data.Active, &data.Active); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Active, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[3], &data.Active, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			m["active"] = x
+		}
+	}
+
+	// Status
+	if keep, err := s.Keep(&firevaultFieldsUser[4], m, data.Status != "", &data.Status); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Status, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[4], &data.Status, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			m["status"] = x
+		}
+	}
+
+	// Score
+	if keep, err := s.Keep(&firevaultFieldsUser[5], m, data.Score != nil, &data.Score); err != nil {
+		return nil, err
+	} else if keep {
+		var x float64
+		ok := data.Score != nil
+		if ok {
+			x = *data.Score
+		}
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[5], &data.Score, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			m["score"] = x
+		}
+	}
+
+	// Owner
+	if keep, err := s.Keep(&firevaultFieldsUser[6], m, data.Owner != "", &data.Owner); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Owner, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[6], &data.Owner, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			m["owner"] = x
+		}
+	}
+
+	// CreatedAt
+	if keep, err := s.Keep(&firevaultFieldsUser[7], m, data.CreatedAt != (time.Time{}), &data.CreatedAt); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.CreatedAt, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[7], &data.CreatedAt, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			m["created_at"] = x
+		}
+	}
+
+	// Address
+	if keep, err := s.Keep(&firevaultFieldsUser[8], m, s.HasValue(&data.Address), &data.Address); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Address, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[8], &data.Address, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			sub, err := firevaultValidateAddress(s.Child(&firevaultFieldsUser[8]), &data.Address)
+			if err != nil {
+				return nil, err
+			}
+
+			m["address"] = sub
+		}
+	}
+
+	// Billing
+	if keep, err := s.Keep(&firevaultFieldsUser[9], m, data.Billing != nil, &data.Billing); err != nil {
+		return nil, err
+	} else if keep {
+		var x Address
+		ok := data.Billing != nil
+		if ok {
+			x = *data.Billing
+		}
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[9], &data.Billing, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			sub, err := firevaultValidateAddress(s.Child(&firevaultFieldsUser[9]), data.Billing)
+			if err != nil {
+				return nil, err
+			}
+
+			m["billing"] = sub
+		}
+	}
+
+	// Previous
+	if keep, err := s.Keep(&firevaultFieldsUser[10], m, data.Previous != nil, &data.Previous); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Previous, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[10], &data.Previous, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			items := make([]interface{}, len(x))
+			scope := s.Child(&firevaultFieldsUser[10])
+
+			for i := range x {
+				sub, err := firevaultValidateAddress(scope.Index(i), &x[i])
+				if err != nil {
+					return nil, err
+				}
+
+				items[i] = sub
+			}
+
+			m["previous"] = items
+		}
+	}
+
+	// Others
+	if keep, err := s.Keep(&firevaultFieldsUser[11], m, data.Others != nil, &data.Others); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Others, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[11], &data.Others, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			items := make([]interface{}, len(x))
+			scope := s.Child(&firevaultFieldsUser[11])
+
+			for i := range x {
+				if x[i] != nil {
+					sub, err := firevaultValidateAddress(scope.Index(i), x[i])
+					if err != nil {
+						return nil, err
+					}
+
+					items[i] = sub
+				}
+			}
+
+			m["others"] = items
+		}
+	}
+
+	// Tags
+	if keep, err := s.Keep(&firevaultFieldsUser[12], m, data.Tags != nil, &data.Tags); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Tags, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[12], &data.Tags, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			m["tags"] = x
+		}
+	}
+
+	// Labels
+	if keep, err := s.Keep(&firevaultFieldsUser[13], m, data.Labels != nil, &data.Labels); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Labels, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[13], &data.Labels, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			items := make(map[string]interface{}, len(x))
+
+			for k, e := range x {
+				items[k] = e
+			}
+
+			m["labels"] = items
+		}
+	}
+
+	// Places
+	if keep, err := s.Keep(&firevaultFieldsUser[14], m, data.Places != nil, &data.Places); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Places, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[14], &data.Places, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			items := make(map[string]interface{}, len(x))
+			scope := s.Child(&firevaultFieldsUser[14])
+
+			for k, e := range x {
+				items[k] = nil
+				if e != nil {
+					sub, err := firevaultValidateAddress(scope.Key(k), e)
+					if err != nil {
+						return nil, err
+					}
+
+					items[k] = sub
+				}
+			}
+
+			m["places"] = items
+		}
+	}
+
+	// Extra
+	if keep, err := s.Keep(&firevaultFieldsUser[15], m, data.Extra != nil, &data.Extra); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Extra, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[15], &data.Extra, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			sub, err := s.Child(&firevaultFieldsUser[15]).Value(x)
+			if err != nil {
+				return nil, err
+			}
+
+			m["extra"] = sub
+		}
+	}
+
+	// Shape
+	if keep, err := s.Keep(&firevaultFieldsUser[16], m, data.Shape != nil, &data.Shape); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Shape, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[16], &data.Shape, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			sub, err := s.Child(&firevaultFieldsUser[16]).Value(x)
+			if err != nil {
+				return nil, err
+			}
+
+			m["shape"] = sub
+		}
+	}
+
+	// Shapes
+	if keep, err := s.Keep(&firevaultFieldsUser[17], m, data.Shapes != nil, &data.Shapes); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Shapes, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[17], &data.Shapes, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			items := make([]interface{}, len(x))
+			scope := s.Child(&firevaultFieldsUser[17])
+
+			for i := range x {
+				sub, err := scope.Index(i).Value(x[i])
+				if err != nil {
+					return nil, err
+				}
+
+				items[i] = sub
+			}
+
+			m["shapes"] = items
+		}
+	}
+
+	// Meta
+	if keep, err := s.Keep(&firevaultFieldsUser[18], m, s.HasValue(&data.Meta), &data.Meta); err != nil {
+		return nil, err
+	} else if keep {
+		x, ok := data.Meta, true
+		x, ok, err = firevault.ApplyGeneratedRules(s, &firevaultFieldsUser[18], &data.Meta, x, ok)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			sub, err := firevaultValidateMeta(s.Child(&firevaultFieldsUser[18]), &data.Meta)
+			if err != nil {
+				return nil, err
+			}
+
+			m["meta"] = sub
+		}
+	}
+
+	return m, nil
+}
+
+// RegisterFirevaultValidators registers the generated validate and
+// marshal functions with the provided Validator.
+func RegisterFirevaultValidators(validator *firevault.Validator) error {
+	if err := firevault.RegisterGenerated(validator, firevaultValidateAddress); err != nil {
+		return err
+	}
+
+	if err := firevault.RegisterGenerated(validator, firevaultValidateMeta); err != nil {
+		return err
+	}
+
+	if err := firevault.RegisterGenerated(validator, firevaultValidateUser); err != nil {
+		return err
+	}
+
+	return nil
+}
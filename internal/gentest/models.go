@@ -0,0 +1,52 @@
+// Package gentest holds models used to test the code generated by
+// firevault-gen against the reflection-based validator.
+package gentest
+
+import "time"
+
+//go:generate go run ../../cmd/firevault-gen -type=User
+
+type Status string
+
+type Address struct {
+	Street string `firevault:"street,required,transform:trim_space" json:"street"`
+	City   string `firevault:"city,omitempty" json:"city"`
+}
+
+type Shape interface {
+	Area() float64
+}
+
+type Circle struct {
+	Radius float64 `firevault:"radius,required,max=100"`
+}
+
+func (c Circle) Area() float64 { return 3.14 * c.Radius * c.Radius }
+
+type Meta struct {
+	Source string `firevault:"source"`
+}
+
+type User struct {
+	Name      string              `firevault:"name,required,min=3,transform:lowercase" json:"name"`
+	Email     string              `firevault:"email,required_create,email,omitempty_update" json:"email"`
+	Age       int                 `firevault:"age,omitempty,max=150" json:"age"`
+	Active    bool                `firevault:"active"`
+	Status    Status              `firevault:"status,omitempty,is_status"`
+	Score     *float64            `firevault:"score,omitempty"`
+	Owner     string              `firevault:"owner,immutable" json:"owner"`
+	CreatedAt time.Time           `firevault:"created_at,omitempty" json:"createdAt"`
+	Address   Address             `firevault:"address" json:"address"`
+	Billing   *Address            `firevault:"billing,omitempty" json:"billing"`
+	Previous  []Address           `firevault:"previous,dive" json:"previous"`
+	Others    []*Address          `firevault:"others,omitempty,dive"`
+	Tags      []string            `firevault:"tags,omitempty,max=3"`
+	Labels    map[string]string   `firevault:"labels,omitempty,dive"`
+	Places    map[string]*Address `firevault:"places,omitempty,dive"`
+	Extra     interface{}         `firevault:"extra,omitempty"`
+	Shape     Shape               `firevault:"shape,omitempty"`
+	Shapes    []Shape             `firevault:"shapes,omitempty,dive"`
+	Meta      `firevault:"meta,omitempty"`
+	Ignored   string `firevault:"-"`
+	Untagged  string
+}
@@ -0,0 +1,178 @@
+package firevault
+
+import (
+	"context"
+	"errors"
+	"iter"
+
+	"cloud.google.com/go/firestore"
+)
+
+// DocumentIterator is an iterator over the Firestore
+// documents which match a Query.
+//
+// Documents are fetched as needed, instead of being
+// loaded into memory all at once. The iterator must
+// be stopped (by calling Stop) once it's no longer
+// needed.
+type DocumentIterator[T interface{}] struct {
+	ctx           context.Context
+	collection    *CollectionRef[T]
+	query         Query
+	tx            *Transaction
+	iter          *firestore.DocumentIterator
+	distanceField string
+	docs          []Document[T] // documents fetched by ID
+	started       bool
+	err           error
+}
+
+// Documents returns an iterator over the Firestore
+// documents which match provided Query.
+//
+// Documents are streamed from Firestore as Next is
+// called, so large results can be processed without
+// loading them into memory all at once. Documents
+// matching an ID clause are fetched together, on the
+// first call to Next.
+//
+// The iterator must be stopped (by calling Stop) once
+// it's no longer needed.
+//
+// To use inside a transaction, pass a transaction
+// instance via Options.
+func (c *CollectionRef[T]) Documents(ctx context.Context, query Query, opts ...Options) *DocumentIterator[T] {
+	it := &DocumentIterator[T]{ctx: ctx, collection: c, query: query}
+
+	if c == nil {
+		it.err = errors.New("firevault: nil CollectionRef")
+		return it
+	}
+
+	valOpts, _, _, _, _ := c.parseOptions(find, opts...)
+	it.tx = valOpts.tx
+
+	return it
+}
+
+// All returns an iterator over the Firestore documents
+// which match provided Query, to be used in a for-range
+// loop.
+//
+// Like Documents, it streams documents from Firestore,
+// and stops fetching them once the loop ends. If an
+// error occurs (including the context being cancelled),
+// it's yielded along with an empty Document, and the
+// iteration ends.
+//
+// To use inside a transaction, pass a transaction
+// instance via Options.
+func (c *CollectionRef[T]) All(ctx context.Context, query Query, opts ...Options) iter.Seq2[Document[T], error] {
+	return func(yield func(Document[T], error) bool) {
+		it := c.Documents(ctx, query, opts...)
+		defer it.Stop()
+
+		for {
+			doc, err := it.Next()
+			if err == Done {
+				return
+			}
+
+			if !yield(doc, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Next returns the next matching document.
+//
+// Its second return value is Done if there are no
+// more documents. Once Next returns Done, or any other
+// error (including the context being cancelled), all
+// subsequent calls return the same error.
+func (it *DocumentIterator[T]) Next() (Document[T], error) {
+	if it.err != nil {
+		return Document[T]{}, it.err
+	}
+
+	// documents may already be buffered, so cancellation is checked explicitly
+	if err := it.ctx.Err(); err != nil {
+		it.fail(err)
+		return Document[T]{}, err
+	}
+
+	if !it.started {
+		it.started = true
+
+		if err := it.start(); err != nil {
+			it.fail(err)
+			return Document[T]{}, err
+		}
+	}
+
+	if len(it.query.ids) > 0 {
+		if len(it.docs) == 0 {
+			it.fail(Done)
+			return Document[T]{}, Done
+		}
+
+		doc := it.docs[0]
+		it.docs = it.docs[1:]
+
+		return doc, nil
+	}
+
+	docSnap, err := it.iter.Next()
+	if err != nil {
+		err = wrapFirestoreError(err)
+		it.fail(err)
+		return Document[T]{}, err
+	}
+
+	doc, err := it.collection.snapshotDoc(docSnap, it.distanceField)
+	if err != nil {
+		it.fail(err)
+		return Document[T]{}, err
+	}
+
+	return doc, nil
+}
+
+// Stop stops the iterator, freeing its resources.
+//
+// It should always be called when the iterator is no
+// longer needed, and can be called multiple times.
+// Subsequent calls to Next return Done (or the error
+// which previously ended the iteration).
+func (it *DocumentIterator[T]) Stop() {
+	if it.err == nil {
+		it.err = Done
+	}
+
+	it.fail(it.err)
+}
+
+// start fetching documents, based on the iterator's Query
+func (it *DocumentIterator[T]) start() error {
+	var err error
+
+	if len(it.query.ids) > 0 {
+		it.docs, err = it.collection.fetchDocsByID(it.ctx, it.tx, it.query.ids)
+		return err
+	}
+
+	it.iter, it.distanceField, err = it.collection.queryIterator(it.ctx, it.tx, it.query)
+	return err
+}
+
+// end iteration with an error, releasing the underlying iterator
+func (it *DocumentIterator[T]) fail(err error) {
+	it.err = err
+	it.docs = nil
+
+	if it.iter != nil {
+		it.iter.Stop()
+		it.iter = nil
+	}
+}
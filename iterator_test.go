@@ -0,0 +1,53 @@
+package firevault
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDocumentIterator(t *testing.T) {
+	users := &CollectionRef[map[string]interface{}]{connection: &Connection{Validator: NewValidator()}, path: "users"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// cancellation is checked before fetching any documents
+	it := users.Documents(ctx, NewQuery().ID("a", "b"))
+	if _, err := it.Next(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Next() error = %v, want %v", err, context.Canceled)
+	}
+
+	if _, err := it.Next(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected error to be returned by subsequent calls, got %v", err)
+	}
+
+	it.Stop()
+	if _, err := it.Next(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Stop to keep previous error, got %v", err)
+	}
+
+	// stopped iterators are done
+	it = users.Documents(context.Background(), NewQuery())
+	it.Stop()
+	it.Stop()
+
+	if _, err := it.Next(); err != Done {
+		t.Errorf("Next() error = %v, want Done", err)
+	}
+
+	// iteration ends after the first error
+	var errs []error
+	for _, err := range users.All(ctx, NewQuery()) {
+		errs = append(errs, err)
+	}
+
+	if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("All() errors = %v, want a single %v", errs, context.Canceled)
+	}
+
+	var nilCollection *CollectionRef[map[string]interface{}]
+	if _, err := nilCollection.Documents(context.Background(), NewQuery()).Next(); err == nil {
+		t.Errorf("Expected error for nil CollectionRef")
+	}
+}
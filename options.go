@@ -24,6 +24,9 @@ type Options struct {
 	mergeFields      []string
 	precondition     firestore.Precondition
 	transaction      *Transaction
+	loadPrevious     bool
+	rejectImmutable  bool
+	cascade          bool
 }
 
 // Create a new Options instance.
@@ -165,10 +168,60 @@ func (o Options) AsUpdate() Options {
 	return o
 }
 
+// Load the currently stored documents before
+// an update, making their values available to
+// validators via FieldScope's Previous method.
+//
+// Matching documents are read first (inside the
+// transaction, if one is provided) and the data
+// is validated once per document, instead of
+// once per call. If validation fails for any
+// document, no updates are applied and the
+// returned error states the ID of each failed
+// document.
+//
+// Outside a transaction, each update requires
+// the document's last update time to match the
+// one that was read, so a document changed in
+// the meantime fails with ErrPreconditionFailed.
+//
+// Note: Transformations are applied once per
+// document, which matters when used with
+// ModifyOriginal.
+//
+// Only applies to the Update and Set methods.
+func (o Options) LoadPrevious() Options {
+	o.loadPrevious = true
+	return o
+}
+
+// Return a FieldError, instead of silently
+// stripping the field, whenever a field with
+// an "immutable" or "createonly" rule is set
+// during an update.
+//
+// When used with LoadPrevious, an "immutable"
+// field which is not already stored, or is set
+// to its stored value, is not rejected.
+// Without a loaded document, any set value is
+// rejected.
+//
+// Only applies to the Validate and Update
+// methods.
+func (o Options) RejectImmutableFields() Options {
+	o.rejectImmutable = true
+	return o
+}
+
 // Specify custom doc ID. If left empty,
 // Firestore will automatically create one.
 //
-// Only applies to the Create method.
+// When passed to the Validate method, the ID
+// is made available to validators via
+// FieldScope's DocumentID method.
+//
+// Only applies to the Create and Validate
+// methods.
 func (o Options) CustomID(id string) Options {
 	o.id = id
 	return o
@@ -186,10 +239,14 @@ func (o Options) CustomID(id string) Options {
 // document was created, some fields may not be
 // deleted.
 //
+// Fields with an "immutable" or "createonly"
+// rule are never deleted, preserving their
+// stored values.
+//
 // This option overrides any previous calls to
 // ReplaceFields.
 //
-// Only applies to the Update method.
+// Only applies to the Update and Set methods.
 func (o Options) ReplaceAll() Options { // previously DisableMerge
 	o.disableMerge = true
 	o.mergeFields = []string{}
@@ -211,7 +268,7 @@ func (o Options) ReplaceAll() Options { // previously DisableMerge
 //
 // Multiple calls to the method are cumulative.
 //
-// Only applies to the Update method.
+// Only applies to the Update and Set methods.
 func (o Options) ReplaceFields(fields ...string) Options { // previously MergeFields
 	o.mergeFields = append(o.mergeFields, fields...)
 	o.disableMerge = false
@@ -265,3 +322,22 @@ func (o Options) Transaction(tx *Transaction) Options {
 	o.transaction = tx
 	return o
 }
+
+// Also delete the documents of all subcollections
+// of matching documents, as registered using the
+// Connection's RegisterSubCollection method
+// (recursively).
+//
+// Subcollection documents are read and deleted
+// (deepest first) before the matching documents, so
+// an interrupted cascade can be completed by a retry.
+// Outside a transaction, the cascade isn't atomic.
+// Any precondition only applies to the matching
+// documents (outside a transaction, their
+// subcollections are deleted even if it fails).
+//
+// Only applies to the Delete method.
+func (o Options) Cascade() Options {
+	o.cascade = true
+	return o
+}
@@ -0,0 +1,401 @@
+package firevault
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// Page holds a page of documents, as fetched by
+// Paginate.
+type Page[T interface{}] struct {
+	// The page's documents.
+	Items []Document[T]
+	// Opaque token used to fetch the next page (via
+	// Query's PageToken method). Empty if there are
+	// no more documents.
+	NextPageToken string
+	// Opaque token used to fetch the previous page
+	// (via Query's PageToken method). Empty if this
+	// is the first page.
+	PrevPageToken string
+}
+
+// contents of a page token, which are signed
+type pageToken struct {
+	Collection string        `json:"c"`
+	Orders     []string      `json:"o"`
+	Values     []cursorValue `json:"v"`
+	Before     bool          `json:"b,omitempty"`
+}
+
+// a single (typed) value of a page token's cursor
+type cursorValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v,omitempty"`
+}
+
+// Paginate fetches a page of Firestore documents which
+// match provided Query, returning at most pageSize
+// documents.
+//
+// The returned Page holds tokens which can be used to
+// fetch the next and previous pages, by passing them to
+// the Query's PageToken method. Tokens encode the order
+// values and ID of the page's boundary documents, and
+// are signed using the Connection's page token key, so
+// they can be safely sent to (and received from)
+// clients.
+//
+// Results are always ordered by ID after the Query's
+// other orders (in the direction of the last one), so
+// each document's position is unique. The ordered
+// fields are added to any selected fields.
+//
+// Paginate can't be used with ID, FindNearest,
+// cursors, limits or offsets.
+//
+// To use inside a transaction, pass a transaction
+// instance via Options.
+func (c *CollectionRef[T]) Paginate(
+	ctx context.Context,
+	query Query,
+	pageSize int,
+	opts ...Options,
+) (Page[T], error) {
+	if c == nil {
+		return Page[T]{}, errors.New("firevault: nil CollectionRef")
+	}
+
+	if pageSize <= 0 {
+		return Page[T]{}, errors.New("firevault: page size must be greater than 0")
+	}
+
+	if len(query.ids) > 0 || query.nearest != nil {
+		return Page[T]{}, errors.New("firevault: Paginate cannot be used with ID or FindNearest")
+	}
+
+	hasCursors := len(query.startAt) > 0 || len(query.startAfter) > 0 ||
+		len(query.endBefore) > 0 || len(query.endAt) > 0
+	if hasCursors || query.limit > 0 || query.limitToLast > 0 || query.offset > 0 {
+		return Page[T]{}, errors.New("firevault: Paginate cannot be used with cursors, limits or offsets")
+	}
+
+	valOpts, _, _, _, _ := c.parseOptions(find, opts...)
+
+	orders, err := c.resolveOrders(query.orders)
+	if err != nil {
+		return Page[T]{}, err
+	}
+
+	// order documents with equal values by ID, so each boundary is unique
+	if len(orders) == 0 || orders[len(orders)-1].path != DocumentID {
+		direction := Asc
+		if len(orders) > 0 {
+			direction = orders[len(orders)-1].direction
+		}
+
+		orders = append(orders, order{DocumentID, direction, false})
+	}
+
+	query.orders = orders
+
+	// order values are needed to create tokens
+	if query.projected {
+		for _, o := range orders {
+			if o.path != DocumentID && !slices.Contains(query.fields, o.path) {
+				query.fields = append(slices.Clip(query.fields), o.path)
+			}
+		}
+	}
+
+	// fetch an extra document, to know whether there are more
+	var token *pageToken
+	query.limit = pageSize + 1
+
+	if query.pageToken != "" {
+		token, err = c.decodePageToken(query.pageToken, orders)
+		if err != nil {
+			return Page[T]{}, err
+		}
+
+		values, err := c.cursorValues(token.Values)
+		if err != nil {
+			return Page[T]{}, err
+		}
+
+		if token.Before {
+			query.endBefore = values
+			query.limit, query.limitToLast = 0, pageSize+1
+		} else {
+			query.startAfter = values
+		}
+	}
+
+	docs, snapshots, err := c.fetchSnapshotsByQuery(ctx, valOpts.tx, query)
+	if err != nil {
+		return Page[T]{}, err
+	}
+
+	before := token != nil && token.Before
+	more := len(docs) > pageSize
+
+	if more && before {
+		docs, snapshots = docs[1:], snapshots[1:]
+	} else if more {
+		docs, snapshots = docs[:pageSize], snapshots[:pageSize]
+	}
+
+	page := Page[T]{Items: docs}
+	if len(docs) == 0 {
+		return page, nil
+	}
+
+	// pages reached going backwards always have a next page
+	if more || before {
+		page.NextPageToken, err = c.encodePageToken(snapshots[len(snapshots)-1], orders, false)
+		if err != nil {
+			return Page[T]{}, err
+		}
+	}
+
+	// pages reached going forwards always have a previous page
+	if (more && before) || (token != nil && !before) {
+		page.PrevPageToken, err = c.encodePageToken(snapshots[0], orders, true)
+		if err != nil {
+			return Page[T]{}, err
+		}
+	}
+
+	return page, nil
+}
+
+// create a signed page token, pointing before (or after) a document
+func (c *CollectionRef[T]) encodePageToken(
+	docSnap *firestore.DocumentSnapshot,
+	orders []order,
+	before bool,
+) (string, error) {
+	values := make([]interface{}, len(orders))
+
+	for i, o := range orders {
+		if o.path == DocumentID {
+			values[i] = docSnap.Ref.ID
+
+			// documents of collection groups are ordered by their full path
+			if c.group != nil {
+				values[i] = docSnap.Ref
+			}
+
+			continue
+		}
+
+		value, err := docSnap.DataAtPath(firestore.FieldPath(strings.Split(o.path, ".")))
+		if err != nil {
+			return "", err
+		}
+
+		values[i] = value
+	}
+
+	return c.newPageToken(values, orders, before)
+}
+
+// create a signed page token, holding provided cursor values
+func (c *CollectionRef[T]) newPageToken(values []interface{}, orders []order, before bool) (string, error) {
+	token := pageToken{
+		Collection: c.tokenCollection(),
+		Orders:     orderKeys(orders),
+		Values:     make([]cursorValue, len(values)),
+		Before:     before,
+	}
+
+	for i, value := range values {
+		cv, err := newCursorValue(value)
+		if err != nil {
+			return "", err
+		}
+
+		token.Values[i] = cv
+	}
+
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := c.signPageToken(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// verify and decode a page token, checking it belongs to the same query
+func (c *CollectionRef[T]) decodePageToken(raw string, orders []order) (*pageToken, error) {
+	invalid := errors.New("firevault: invalid page token")
+
+	encodedPayload, encodedSignature, ok := strings.Cut(raw, ".")
+	if !ok {
+		return nil, invalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, invalid
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return nil, invalid
+	}
+
+	expected, err := c.signPageToken(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal(signature, expected) {
+		return nil, invalid
+	}
+
+	var token pageToken
+	if err := json.Unmarshal(payload, &token); err != nil {
+		return nil, invalid
+	}
+
+	if token.Collection != c.tokenCollection() || !slices.Equal(token.Orders, orderKeys(orders)) ||
+		len(token.Values) != len(orders) {
+		return nil, errors.New("firevault: page token does not match query")
+	}
+
+	return &token, nil
+}
+
+// get the collection (or collection group) identified by page tokens
+func (c *CollectionRef[T]) tokenCollection() string {
+	if c.group != nil {
+		return "*/" + c.path // group IDs can't contain slashes
+	}
+
+	return c.path
+}
+
+// get signature of a page token's payload
+func (c *CollectionRef[T]) signPageToken(payload []byte) ([]byte, error) {
+	if len(c.connection.pageTokenKey) == 0 {
+		return nil, errors.New("firevault: page token key is not set")
+	}
+
+	mac := hmac.New(sha256.New, c.connection.pageTokenKey)
+	mac.Write(payload)
+
+	return mac.Sum(nil), nil
+}
+
+// get values of a page token's cursor, as accepted by Firestore
+func (c *CollectionRef[T]) cursorValues(cursor []cursorValue) ([]interface{}, error) {
+	values := make([]interface{}, len(cursor))
+	invalid := errors.New("firevault: invalid page token")
+
+	for i, cv := range cursor {
+		var err error
+
+		switch cv.Type {
+		case "null":
+			values[i] = nil
+		case "bool":
+			values[i], err = strconv.ParseBool(cv.Value)
+		case "int":
+			values[i], err = strconv.ParseInt(cv.Value, 10, 64)
+		case "float":
+			values[i], err = strconv.ParseFloat(cv.Value, 64)
+		case "string":
+			values[i] = cv.Value
+		case "time":
+			values[i], err = time.Parse(time.RFC3339Nano, cv.Value)
+		case "bytes":
+			values[i], err = base64.StdEncoding.DecodeString(cv.Value)
+		case "ref":
+			// references are stored using their path, relative to the database
+			if c.connection.client == nil {
+				return nil, invalid
+			}
+
+			ref := c.connection.client.Doc(cv.Value)
+			if ref == nil {
+				return nil, invalid
+			}
+
+			values[i] = ref
+		case "geopoint":
+			lat, lng, _ := strings.Cut(cv.Value, ",")
+			point := &latlng.LatLng{}
+
+			point.Latitude, err = strconv.ParseFloat(lat, 64)
+			if err == nil {
+				point.Longitude, err = strconv.ParseFloat(lng, 64)
+			}
+
+			values[i] = point
+		default:
+			return nil, invalid
+		}
+
+		if err != nil {
+			return nil, invalid
+		}
+	}
+
+	return values, nil
+}
+
+// get typed representation of a Firestore value, used in page tokens
+func newCursorValue(value interface{}) (cursorValue, error) {
+	switch v := value.(type) {
+	case nil:
+		return cursorValue{Type: "null"}, nil
+	case bool:
+		return cursorValue{"bool", strconv.FormatBool(v)}, nil
+	case int64:
+		return cursorValue{"int", strconv.FormatInt(v, 10)}, nil
+	case float64:
+		return cursorValue{"float", strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	case string:
+		return cursorValue{"string", v}, nil
+	case time.Time:
+		return cursorValue{"time", v.Format(time.RFC3339Nano)}, nil
+	case []byte:
+		return cursorValue{"bytes", base64.StdEncoding.EncodeToString(v)}, nil
+	case *firestore.DocumentRef:
+		return cursorValue{"ref", relativePath(v)}, nil
+	case *latlng.LatLng:
+		return cursorValue{"geopoint", fmt.Sprintf("%v,%v", v.Latitude, v.Longitude)}, nil
+	}
+
+	return cursorValue{}, fmt.Errorf("firevault: cannot paginate by a field of type %T", value)
+}
+
+// get keys identifying the orders of a query
+func orderKeys(orders []order) []string {
+	keys := make([]string, len(orders))
+
+	for i, o := range orders {
+		keys[i] = o.path + " " + strconv.Itoa(int(o.direction))
+	}
+
+	return keys
+}
@@ -0,0 +1,112 @@
+package firevault
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func TestPageTokens(t *testing.T) {
+	connection := &Connection{Validator: NewValidator()}
+	if err := connection.SetPageTokenKey(make([]byte, 16)); err == nil {
+		t.Errorf("Expected error for short page token key")
+	}
+
+	if err := connection.SetPageTokenKey([]byte(strings.Repeat("k", 32))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	users := &CollectionRef[map[string]interface{}]{connection: connection, path: "users"}
+	orders := []order{{"age", Desc, false}, {DocumentID, Desc, false}}
+
+	values := []interface{}{
+		nil,
+		true,
+		int64(42),
+		0.1,
+		"Bobby",
+		time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+		[]byte("bytes"),
+		&latlng.LatLng{Latitude: 51.5, Longitude: -0.12},
+	}
+	tokenOrders := make([]order, len(values))
+	for i := range tokenOrders {
+		tokenOrders[i] = order{"field", Asc, false}
+	}
+
+	raw, err := users.newPageToken(values, tokenOrders, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	token, err := users.decodePageToken(raw, tokenOrders)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoded, err := users.cursorValues(token.Values)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !token.Before || !reflect.DeepEqual(decoded, values) {
+		t.Errorf("Decoded values = %v, want %v", decoded, values)
+	}
+
+	// tokens can't be modified, or used by other queries
+	raw, _ = users.newPageToken([]interface{}{int64(30), "id"}, orders, false)
+	payload, signature, _ := strings.Cut(raw, ".")
+
+	tests := map[string]func() error{
+		"tampered": func() error {
+			decoded, _ := base64.RawURLEncoding.DecodeString(payload)
+			tampered := strings.Replace(string(decoded), "30", "31", 1)
+			_, err := users.decodePageToken(base64.RawURLEncoding.EncodeToString([]byte(tampered))+"."+signature, orders)
+			return err
+		},
+		"unsigned": func() error {
+			_, err := users.decodePageToken(payload, orders)
+			return err
+		},
+		"other order": func() error {
+			_, err := users.decodePageToken(raw, orders[1:])
+			return err
+		},
+		"other collection": func() error {
+			_, err := (&CollectionRef[map[string]interface{}]{connection: connection, path: "admins"}).decodePageToken(raw, orders)
+			return err
+		},
+		"other key": func() error {
+			other := &Connection{pageTokenKey: []byte(strings.Repeat("x", 32))}
+			_, err := (&CollectionRef[map[string]interface{}]{connection: other, path: "users"}).decodePageToken(raw, orders)
+			return err
+		},
+	}
+
+	for name, decode := range tests {
+		if decode() == nil {
+			t.Errorf("Expected error for %s token", name)
+		}
+	}
+
+	if _, err := newCursorValue(map[string]interface{}{}); err == nil {
+		t.Errorf("Expected error for unsupported cursor value")
+	}
+
+	invalid := []Query{
+		NewQuery().ID("a"),
+		NewQuery().Limit(5),
+		NewQuery().OrderBy("age", Asc).StartAfter(30),
+	}
+
+	for _, query := range invalid {
+		if _, err := users.Paginate(context.Background(), query, 10); err == nil {
+			t.Errorf("Expected error for unsupported Query %+v", query)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package firevault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+)
+
+// DiscriminatorField is the name of the field
+// written alongside the data of registered types,
+// whenever they're validated through an interface
+// (i.e. as an interface-typed field, or as the
+// document of a CollectionRef with an interface
+// type). It holds the type's registered key and
+// is used to decode the data back into the right
+// concrete type.
+const DiscriminatorField = "_type"
+
+// register a concrete type under a key
+func (v *validator) registerType(key string, value interface{}) error {
+	if v == nil {
+		return errors.New("firevault: nil validator")
+	}
+
+	if len(key) == 0 {
+		return errors.New("firevault: type key cannot be empty")
+	}
+
+	if value == nil {
+		return errors.New("firevault: type " + key + " cannot be nil")
+	}
+
+	typ := derefType(reflect.TypeOf(value))
+	if typ.Kind() != reflect.Struct {
+		return errors.New("firevault: type " + key + " must be a struct")
+	}
+
+	sd, ok := v.cache.get(typ)
+	if !ok {
+		var err error
+		sd, err = v.extractStructData(&fieldScope{typ: typ, value: reflect.New(typ).Elem()})
+		if err != nil {
+			return err
+		}
+	}
+
+	// discriminator can't be overwritten by a field
+	for _, fs := range sd.fields {
+		if fs.field == DiscriminatorField {
+			return fmt.Errorf("firevault: type %s cannot have a %s field", key, DiscriminatorField)
+		}
+	}
+
+	// remove previous registrations of either the key or the type
+	maps.DeleteFunc(v.types, func(k string, t reflect.Type) bool { return k == key || t == typ })
+	maps.DeleteFunc(v.typeKeys, func(t reflect.Type, k string) bool { return k == key || t == typ })
+
+	v.types[key] = typ
+	v.typeKeys[typ] = key
+	return nil
+}
+
+// get the concrete struct value held by an interface value, and its registered key
+func (v *validator) concreteValue(val reflect.Value) (reflect.Value, string) {
+	for val.Kind() == reflect.Interface || val.Kind() == reflect.Pointer {
+		if val.IsNil() {
+			return reflect.Value{}, ""
+		}
+
+		val = val.Elem()
+	}
+
+	if !val.IsValid() {
+		return val, ""
+	}
+
+	key, ok := v.typeKeys[val.Type()]
+	if !ok {
+		return val, ""
+	}
+
+	// values held by interfaces aren't addressable
+	return addressable(val), key
+}
+
+// process interface's value, validating registered concrete types
+func (v *validator) processInterfaceValue(
+	ctx context.Context,
+	fs *fieldScope,
+	opts validationOpts,
+) (interface{}, error) {
+	if fs.value.IsNil() {
+		return nil, nil
+	}
+
+	concrete, key := v.concreteValue(fs.value)
+	if !concrete.IsValid() {
+		return nil, nil
+	}
+
+	// return unregistered types directly, without validating nested fields
+	if key == "" {
+		if value, ok, err := v.marshalValue(concrete); ok {
+			return value, err
+		}
+
+		return fs.value.Interface(), nil
+	}
+
+	structFs := &fieldScope{
+		collPath:    fs.collPath,
+		strct:       fs.strct,
+		field:       fs.field,
+		structField: fs.structField,
+		path:        fs.path,
+		structPath:  fs.structPath,
+		jsonField:   fs.jsonField,
+		jsonPath:    fs.jsonPath,
+		jsonPointer: fs.jsonPointer,
+		value:       concrete,
+		kind:        reflect.Struct,
+		typ:         concrete.Type(),
+		parent:      fs.parent,
+		opts:        fs.opts,
+		dynamic:     true, // same type can be held at any path
+	}
+
+	// use previously stored value, if of the same type
+	if prev, _ := v.concreteValue(fs.prev); prev.IsValid() && prev.Type() == structFs.typ {
+		structFs.prev = prev
+	}
+
+	dataMap, err := v.validateStructFields(ctx, structFs, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	dataMap[DiscriminatorField] = key
+	return dataMap, nil
+}
+
+// decode a registered type, held by an interface
+func (v *validator) decodeConcrete(dst reflect.Value, key string, data map[string]interface{}) error {
+	typ, ok := v.types[key]
+	if !ok {
+		return fmt.Errorf("firevault: type %s is not registered", key)
+	}
+
+	ptr := reflect.New(typ)
+	if err := v.decodeStruct(ptr.Elem(), data); err != nil {
+		return err
+	}
+
+	switch {
+	case typ.AssignableTo(dst.Type()):
+		dst.Set(ptr.Elem())
+	case ptr.Type().AssignableTo(dst.Type()):
+		dst.Set(ptr)
+	default:
+		return fmt.Errorf("firevault: type %s does not implement %s", typ, dst.Type())
+	}
+
+	return nil
+}
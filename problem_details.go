@@ -0,0 +1,123 @@
+package firevault
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ProblemDetails represents an RFC 9457
+// problem details response body, which can
+// be used to report validation failures to
+// HTTP API clients.
+//
+// Field errors are reported using the json
+// tag names of the struct, rather than the
+// Firevault or Go field names.
+type ProblemDetails struct {
+	Type     string      `json:"type,omitempty"`
+	Title    string      `json:"title,omitempty"`
+	Status   int         `json:"status,omitempty"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Errors   FieldErrors `json:"errors,omitempty"`
+}
+
+// Create a new ProblemDetails instance from
+// the provided error and HTTP status code.
+//
+// If the error is (or wraps) a FieldError or
+// FieldErrors, they are included in the
+// "errors" member of the response. Otherwise,
+// the error message is used as the detail.
+//
+// If the status code is 0, 422 (Unprocessable
+// Entity) is used for field errors, a matching
+// status for Firevault's sentinel errors (e.g.
+// 404 for ErrNotFound), and 500 (Internal Server
+// Error) for all others.
+func NewProblemDetails(err error, status int) ProblemDetails {
+	fieldErrs := collectFieldErrors(err)
+
+	if status == 0 {
+		status = errorStatus(err)
+		if len(fieldErrs) > 0 {
+			status = http.StatusUnprocessableEntity
+		}
+	}
+
+	pd := ProblemDetails{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Errors: fieldErrs,
+	}
+
+	if len(fieldErrs) > 0 {
+		pd.Detail = "firevault: validation failed"
+	} else if err != nil {
+		pd.Detail = err.Error()
+	}
+
+	return pd
+}
+
+// Write encodes the ProblemDetails as JSON to
+// the provided ResponseWriter, setting the
+// "application/problem+json" content type and
+// the status code.
+func (pd ProblemDetails) Write(w http.ResponseWriter) error {
+	status := pd.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+
+	return json.NewEncoder(w).Encode(pd)
+}
+
+// extract all field errors wrapped by an error
+func collectFieldErrors(err error) FieldErrors {
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrs FieldErrors
+	if errors.As(err, &fieldErrs) {
+		return fieldErrs
+	}
+
+	// errors joined together (e.g. during bulk operations)
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			fieldErrs = append(fieldErrs, collectFieldErrors(e)...)
+		}
+
+		return fieldErrs
+	}
+
+	var fieldErr FieldError
+	if errors.As(err, &fieldErr) {
+		return FieldErrors{fieldErr}
+	}
+
+	return nil
+}
+
+// get the HTTP status code matching an error
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrAlreadyExists), errors.Is(err, ErrAborted):
+		return http.StatusConflict
+	case errors.Is(err, ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, ErrInvalidArgument):
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
@@ -1,12 +1,20 @@
 package firevault
 
+import (
+	"errors"
+	"reflect"
+	"slices"
+
+	"cloud.google.com/go/firestore"
+)
+
 // Query helps to filter and order Firestore documents.
 //
 // Query values are immutable. Each Query method creates
 // a new Query - it does not modify the old.
 type Query struct {
 	ids         []string
-	filters     []filter
+	filters     []Filter
 	orders      []order
 	startAt     []interface{}
 	startAfter  []interface{}
@@ -15,19 +23,86 @@ type Query struct {
 	limit       int
 	limitToLast int
 	offset      int
+	nearest     *nearest
+	fields      []string
+	projected   bool
+	pageToken   string
+}
+
+// Filter represents a condition, or a composite of
+// conditions, used to filter the results of a Query.
+//
+// Filters are created using the Condition, Or and And
+// functions, and can be nested.
+type Filter interface {
+	entityFilter(v *validator, typ reflect.Type) (firestore.EntityFilter, error)
 }
 
+// Operator is used to compare a field's value to
+// the value of a filter.
+type Operator string
+
+const (
+	// Equal matches fields equal to the value.
+	Equal Operator = "=="
+	// NotEqual matches fields not equal to the value.
+	NotEqual Operator = "!="
+	// LessThan matches fields less than the value.
+	LessThan Operator = "<"
+	// LessThanOrEqual matches fields less than or
+	// equal to the value.
+	LessThanOrEqual Operator = "<="
+	// GreaterThan matches fields greater than the value.
+	GreaterThan Operator = ">"
+	// GreaterThanOrEqual matches fields greater than
+	// or equal to the value.
+	GreaterThanOrEqual Operator = ">="
+	// ArrayContains matches array fields containing
+	// the value.
+	ArrayContains Operator = "array-contains"
+	// ArrayContainsAny matches array fields containing
+	// any of the values (provided as a slice).
+	ArrayContainsAny Operator = "array-contains-any"
+	// In matches fields equal to any of the values
+	// (provided as a slice).
+	In Operator = "in"
+	// NotIn matches fields not equal to any of the
+	// values (provided as a slice).
+	NotIn Operator = "not-in"
+)
+
 // represents a single filter in a Query
 type filter struct {
 	path     string
 	operator string
 	value    interface{}
+	byStruct bool
+}
+
+// represents an OR (or AND) of multiple filters in a Query
+type compositeFilter struct {
+	or      bool
+	filters []Filter
+}
+
+// field to which Firestore outputs vector distances, unless specified
+const defaultDistanceField = "_distance"
+
+// represents a vector similarity search in a Query
+type nearest struct {
+	path        string
+	vector      interface{}
+	limit       int
+	measure     DistanceMeasure
+	threshold   *float64
+	resultField string
 }
 
 // represents a single order in a Query
 type order struct {
 	path      string
 	direction Direction
+	byStruct  bool
 }
 
 // Create a new Query instance.
@@ -71,10 +146,131 @@ func (q Query) ID(ids ...string) Query {
 // ">", ">=", "array-contains", "array-contains-any", "in" or
 // "not-in".
 func (q Query) Where(path string, operator string, value interface{}) Query {
-	q.filters = append(q.filters, filter{path, operator, value})
+	q.filters = append(q.filters, filter{path, operator, value, false})
+	return q
+}
+
+// WhereField returns a new Query that filters the set of
+// results, referring to the field by its Go struct path,
+// instead of its stored path.
+//
+// The path argument is a dot-separated sequence of the
+// model's struct field names (e.g. "Address.City"), and is
+// resolved to the stored path (based on the firevault tags)
+// when the query is executed. Fields of embedded structs
+// can be referred to directly, like in Go. Map keys are
+// used as they are.
+//
+// An error is returned when the query is executed if the
+// path does not exist on the model, or if the operator is
+// invalid for the field's type (e.g. ArrayContains on a
+// non-slice field).
+func (q Query) WhereField(path string, operator Operator, value interface{}) Query {
+	q.filters = append(q.filters, filter{path, string(operator), value, true})
+	return q
+}
+
+// WhereFilter returns a new Query that filters the set of
+// results, using a (possibly composite) Filter.
+//
+// Like Where, it can be called multiple times, with all
+// filters having to match (AND condition).
+func (q Query) WhereFilter(filter Filter) Query {
+	q.filters = append(q.filters, filter)
 	return q
 }
 
+// Condition creates a Filter with a single condition,
+// to be used by Or, And or Query's WhereFilter.
+//
+// Its arguments are the same as those of Query's
+// Where method.
+func Condition(path string, operator string, value interface{}) Filter {
+	return filter{path, operator, value, false}
+}
+
+// FieldCondition creates a Filter with a single
+// condition, to be used by Or, And or Query's
+// WhereFilter.
+//
+// Its arguments are the same as those of Query's
+// WhereField method.
+func FieldCondition(path string, operator Operator, value interface{}) Filter {
+	return filter{path, string(operator), value, true}
+}
+
+// Or creates a Filter which matches documents that
+// match at least one of the provided filters.
+func Or(filters ...Filter) Filter {
+	return compositeFilter{true, filters}
+}
+
+// And creates a Filter which matches documents that
+// match all of the provided filters.
+//
+// It's only needed when nesting conditions within an
+// Or, as calls to Where and WhereFilter are already
+// combined using AND.
+func And(filters ...Filter) Filter {
+	return compositeFilter{false, filters}
+}
+
+// get Firestore filter of a single condition, on a field of the provided type
+func (f filter) entityFilter(v *validator, typ reflect.Type) (firestore.EntityFilter, error) {
+	path := f.path
+
+	if f.byStruct {
+		var fieldType reflect.Type
+		var err error
+
+		path, fieldType, err = v.resolveFieldPath(typ, f.path, true)
+		if err != nil {
+			return nil, err
+		}
+
+		err = v.checkOperator(f.path, Operator(f.operator), fieldType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// use Firestore values of custom types
+	value, err := v.marshalQueryValue(f.value)
+	if err != nil {
+		return nil, err
+	}
+
+	return firestore.PropertyFilter{Path: path, Operator: f.operator, Value: value}, nil
+}
+
+// get Firestore filter of a composite filter (and its nested filters)
+func (f compositeFilter) entityFilter(v *validator, typ reflect.Type) (firestore.EntityFilter, error) {
+	if len(f.filters) == 0 {
+		return nil, errors.New("firevault: composite filter must contain at least one filter")
+	}
+
+	filters := make([]firestore.EntityFilter, len(f.filters))
+
+	for i, nested := range f.filters {
+		if nested == nil {
+			return nil, errors.New("firevault: composite filter cannot contain a nil filter")
+		}
+
+		entityFilter, err := nested.entityFilter(v, typ)
+		if err != nil {
+			return nil, err
+		}
+
+		filters[i] = entityFilter
+	}
+
+	if f.or {
+		return firestore.OrFilter{Filters: filters}, nil
+	}
+
+	return firestore.AndFilter{Filters: filters}, nil
+}
+
 // OrderBy returns a new Query that specifies the order in which
 // results are returned. A Query can have multiple OrderBy
 // specifications. It appends the specification to the list of
@@ -87,7 +283,18 @@ func (q Query) Where(path string, operator string, value interface{}) Query {
 // To order by document name, use the special field path
 // DocumentID.
 func (q Query) OrderBy(path string, direction Direction) Query {
-	q.orders = append(q.orders, order{path, direction})
+	q.orders = append(q.orders, order{path, direction, false})
+	return q
+}
+
+// OrderByField returns a new Query that specifies the order
+// in which results are returned, referring to the field by
+// its Go struct path, instead of its stored path.
+//
+// The path argument is resolved in the same way as by
+// WhereField.
+func (q Query) OrderByField(path string, direction Direction) Query {
+	q.orders = append(q.orders, order{path, direction, true})
 	return q
 }
 
@@ -183,3 +390,104 @@ func (q Query) Offset(num int) Query {
 	q.offset = num
 	return q
 }
+
+// Select returns a new Query that specifies the fields to
+// return from the result documents. Only the selected
+// fields of each Document's Data are populated.
+//
+// Each path can be a single field or a dot-separated
+// sequence of fields, using the names of the model's
+// firevault tags, and must exist on the model.
+//
+// Calling Select without any paths returns only the
+// IDs of the documents. Calling Select overrides a
+// previous call to Select.
+//
+// Select is ignored when filtering by ID, as well as
+// by Update, which validates against full documents.
+func (q Query) Select(paths ...string) Query {
+	q.fields = slices.Clone(paths)
+	q.projected = true
+	return q
+}
+
+// PageToken returns a new Query that resumes from a page
+// token, returned by a previous call to Paginate (as a
+// Page's NextPageToken or PrevPageToken).
+//
+// An empty token returns the first page, so tokens can be
+// passed as they're received (e.g. from the query
+// parameters of an HTTP request).
+//
+// Tokens are only accepted by queries of the same
+// collection, with the same order. The token is only used
+// by Paginate, and is ignored by other methods.
+func (q Query) PageToken(token string) Query {
+	q.pageToken = token
+	return q
+}
+
+// FindNearest returns a new Query that performs a vector
+// similarity (nearest-neighbour) search, returning at most
+// limit documents, ordered by their distance from the
+// provided vector.
+//
+// The path argument must point to a field holding a
+// Vector32 or Vector64 value, of the same dimension as
+// the vector argument. Documents with vectors of other
+// types (e.g. []float32) are ignored.
+//
+// The vector argument can be a Vector32, Vector64,
+// []float32 or []float64.
+//
+// Each document's distance is available through its
+// Metadata. FindNearest can be combined with filters,
+// but not with ordering, cursors or other limits, and can't
+// be used inside a transaction, or by Count.
+func (q Query) FindNearest(path string, vector interface{}, limit int, measure DistanceMeasure) Query {
+	n := q.copyNearest()
+	n.path, n.vector, n.limit, n.measure = path, vector, limit, measure
+
+	q.nearest = n
+	return q
+}
+
+// DistanceThreshold returns a new Query that excludes
+// documents less similar than the threshold, during a
+// FindNearest search.
+//
+// For DistanceMeasureEuclidean and DistanceMeasureCosine,
+// only documents with a distance less than or equal to the
+// threshold are returned. For DistanceMeasureDotProduct,
+// only those with a distance greater than or equal to it.
+func (q Query) DistanceThreshold(threshold float64) Query {
+	n := q.copyNearest()
+	n.threshold = &threshold
+
+	q.nearest = n
+	return q
+}
+
+// DistanceResultField returns a new Query that specifies
+// the name of the document field to which Firestore outputs
+// the distance, during a FindNearest search.
+//
+// It defaults to "_distance". If the field is part of the
+// model, it's also populated with the distance.
+func (q Query) DistanceResultField(field string) Query {
+	n := q.copyNearest()
+	n.resultField = field
+
+	q.nearest = n
+	return q
+}
+
+// copy vector search, so previous Query isn't modified
+func (q Query) copyNearest() *nearest {
+	if q.nearest == nil {
+		return &nearest{}
+	}
+
+	n := *q.nearest
+	return &n
+}
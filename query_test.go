@@ -0,0 +1,187 @@
+package firevault
+
+import (
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+)
+
+func TestFindNearest(t *testing.T) {
+	threshold := 0.5
+
+	base := NewQuery().Where("category", "==", "books")
+	query := base.DistanceThreshold(threshold).FindNearest("embedding", []float32{1, 2}, 5, DistanceMeasureCosine)
+	named := query.DistanceResultField("score")
+
+	want := &nearest{
+		path:      "embedding",
+		vector:    []float32{1, 2},
+		limit:     5,
+		measure:   DistanceMeasureCosine,
+		threshold: &threshold,
+	}
+	if !reflect.DeepEqual(query.nearest, want) {
+		t.Errorf("Vector search = %+v, want %+v", query.nearest, want)
+	}
+
+	// previous queries aren't modified
+	if base.nearest != nil || query.nearest.resultField != "" || named.nearest.resultField != "score" {
+		t.Errorf("Expected each Query to hold its own vector search")
+	}
+}
+
+func TestCompositeFilters(t *testing.T) {
+	v := newValidator()
+
+	base := NewQuery().Where("age", ">=", 18)
+	query := base.WhereFilter(Or(
+		Condition("status", "==", "open"),
+		And(Condition("assignee", "==", "me"), Condition("priority", "in", []int{1, 2})),
+	))
+
+	if len(base.filters) != 1 || len(query.filters) != 2 {
+		t.Fatalf("Expected each Query to hold its own filters")
+	}
+
+	got, err := query.filters[1].entityFilter(v, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := firestore.OrFilter{Filters: []firestore.EntityFilter{
+		firestore.PropertyFilter{Path: "status", Operator: "==", Value: "open"},
+		firestore.AndFilter{Filters: []firestore.EntityFilter{
+			firestore.PropertyFilter{Path: "assignee", Operator: "==", Value: "me"},
+			firestore.PropertyFilter{Path: "priority", Operator: "in", Value: []int{1, 2}},
+		}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter = %+v, want %+v", got, want)
+	}
+
+	if _, err := Or().entityFilter(v, nil); err == nil {
+		t.Errorf("Expected error for empty composite filter")
+	}
+
+	if _, err := And(Condition("a", "==", 1), nil).entityFilter(v, nil); err == nil {
+		t.Errorf("Expected error for nil nested filter")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	type address struct {
+		City string `firevault:"city"`
+	}
+
+	type audit struct {
+		CreatedBy string `firevault:"created_by"`
+	}
+
+	type user struct {
+		Name    string            `firevault:"name"`
+		Address address           `firevault:"address"`
+		Tags    []string          `firevault:"tags"`
+		Extra   map[string]string `firevault:"extra"`
+		Ignored string
+		audit   `firevault:",inline"`
+	}
+
+	v := newValidator()
+
+	base := NewQuery()
+	query := base.Select("name", "address.city")
+
+	if base.projected || !query.projected || len(query.fields) != 2 {
+		t.Errorf("Expected each Query to hold its own projection")
+	}
+
+	valid := []string{"name", "address", "address.city", "extra.anything", "created_by", DocumentID}
+	for _, path := range valid {
+		if _, _, err := v.resolveFieldPath(reflect.TypeFor[user](), path, false); err != nil {
+			t.Errorf("Unexpected error for path %s: %v", path, err)
+		}
+	}
+
+	invalid := []string{"", "Name", "Ignored", "address.street", "tags.0", "name.first"}
+	for _, path := range invalid {
+		if _, _, err := v.resolveFieldPath(reflect.TypeFor[user](), path, false); err == nil {
+			t.Errorf("Expected error for path %s", path)
+		}
+	}
+}
+
+func TestWhereField(t *testing.T) {
+	type address struct {
+		City string `firevault:"city"`
+	}
+
+	type meta struct {
+		Source string `firevault:"source"`
+	}
+
+	type audit struct {
+		CreatedBy string `firevault:"created_by"`
+	}
+
+	type user struct {
+		Name    string            `firevault:"name"`
+		Address *address          `firevault:"address"`
+		Tags    []string          `firevault:"tags"`
+		Extra   map[string]string `firevault:"extra"`
+		Meta    meta              `firevault:",inline"`
+		audit   `firevault:",inline"`
+	}
+
+	v := newValidator()
+	typ := reflect.TypeFor[user]()
+
+	tests := []struct {
+		path     string
+		operator Operator
+		want     string
+	}{
+		{"Name", Equal, "name"},
+		{"Address.City", In, "address.city"},
+		{"Tags", ArrayContains, "tags"},
+		{"Extra.Anything", NotEqual, "extra.Anything"},
+		{"Meta.Source", Equal, "source"},
+		{"CreatedBy", GreaterThan, "created_by"},
+		{DocumentID, Equal, DocumentID},
+	}
+
+	for _, tc := range tests {
+		got, err := FieldCondition(tc.path, tc.operator, "x").entityFilter(v, typ)
+		if err != nil {
+			t.Errorf("Unexpected error for path %s: %v", tc.path, err)
+			continue
+		}
+
+		if path := got.(firestore.PropertyFilter).Path; path != tc.want {
+			t.Errorf("Path %s resolved to %s, want %s", tc.path, path, tc.want)
+		}
+	}
+
+	invalid := []struct {
+		path     string
+		operator Operator
+	}{
+		{"name", Equal},            // stored name
+		{"Address.Street", Equal},  // missing field
+		{"Source", Equal},          // non-embedded inlined struct
+		{"Name", ArrayContains},    // not a slice
+		{"Tags", Operator("like")}, // unknown operator
+	}
+
+	for _, tc := range invalid {
+		if _, err := FieldCondition(tc.path, tc.operator, "x").entityFilter(v, typ); err == nil {
+			t.Errorf("Expected error for path %s with operator %s", tc.path, tc.operator)
+		}
+	}
+
+	// plain conditions are used as they are
+	got, err := Condition("anything", "==", 1).entityFilter(v, typ)
+	if err != nil || got.(firestore.PropertyFilter).Path != "anything" {
+		t.Errorf("Expected plain condition path to be unchanged")
+	}
+}
@@ -11,6 +11,8 @@ var (
 	upperDigitBoundaryRegex = regexCompileOnce(`([A-Z])([0-9])`)
 	lowerDigitBoundaryRegex = regexCompileOnce(`([a-z])([0-9])`)
 	digitInstanceRegex      = regexCompileOnce(`\d`)
+	rulesIdentifierRegex    = regexCompileOnce(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	indexURLRegex           = regexCompileOnce(`https://console\.firebase\.google\.com/\S*/firestore/indexes\?create_(?:composite|exemption)=`)
 )
 
 // compile regex exp once and return it
@@ -0,0 +1,399 @@
+package firevault
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSON Schema dialect used by generated schemas
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema represents a JSON Schema (draft 2020-12)
+// object, as generated from a struct's Firevault
+// tags.
+//
+// Property names use the struct's json tag names
+// (falling back to the Go field name), matching
+// the encoding of HTTP request bodies.
+type Schema map[string]interface{}
+
+// SchemaRuleFunc is the function that's executed
+// to contribute a schema fragment for a custom
+// validation rule, whenever a schema is
+// generated.
+//
+// It receives the rule's param (if any) and the
+// schema of the field, which can be modified
+// directly.
+type SchemaRuleFunc func(param string, schema Schema)
+
+// register a schema rule
+func (v *validator) registerSchemaRule(name string, schemaRule SchemaRuleFunc) error {
+	if v == nil {
+		return errors.New("firevault: nil validator")
+	}
+
+	if len(name) == 0 {
+		return errors.New("firevault: schema rule name cannot be empty")
+	}
+
+	if schemaRule == nil {
+		return errors.New("firevault: schema rule function " + name + " cannot be empty")
+	}
+
+	v.schemaRules[name] = schemaRule
+	return nil
+}
+
+// Generate a JSON Schema (draft 2020-12) from
+// the Firevault tags of the provided struct type.
+//
+// Each method ("create", "update" or "validate")
+// produces a different schema, as method-specific
+// rules (e.g. "required_create" and
+// "omitempty_update") are applied accordingly.
+//
+// Rules without a JSON Schema equivalent, which
+// have no registered schema rule, are listed in
+// the "x-firevault-rules" member of the field's
+// schema.
+func JSONSchema[T interface{}](validator *Validator, method string) (Schema, error) {
+	if validator == nil {
+		return nil, errors.New("firevault: nil Validator")
+	}
+
+	schema, err := validator.validator.generateSchema(reflect.TypeFor[T](), method)
+	if err != nil {
+		return nil, err
+	}
+
+	schema["$schema"] = jsonSchemaDialect
+	return schema, nil
+}
+
+// Generate OpenAPI 3.1 component schemas from
+// the Firevault tags of the provided struct type.
+//
+// Separate create and update schemas are
+// returned, keyed by the struct's name with a
+// "Create" and "Update" suffix respectively
+// (e.g. "UserCreate" and "UserUpdate"), ready
+// to be merged into the "components.schemas"
+// object of an OpenAPI document.
+func OpenAPISchemas[T interface{}](validator *Validator) (map[string]Schema, error) {
+	if validator == nil {
+		return nil, errors.New("firevault: nil Validator")
+	}
+
+	typ := reflect.TypeFor[T]()
+	schemas := make(map[string]Schema, 2)
+
+	for _, method := range []methodType{create, update} {
+		schema, err := validator.validator.generateSchema(typ, string(method))
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.ToUpper(string(method[0])) + string(method[1:])
+		schemas[typ.Name()+name] = schema
+	}
+
+	return schemas, nil
+}
+
+// generate schema for a struct type and method
+func (v *validator) generateSchema(typ reflect.Type, method string) (Schema, error) {
+	if v == nil {
+		return nil, errors.New("firevault: nil validator")
+	}
+
+	mt := methodType(method)
+	if mt != create && mt != update && mt != validate {
+		return nil, errors.New("firevault: schema method must be one of create, update or validate")
+	}
+
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() != reflect.Struct {
+		return nil, errors.New("firevault: schema type must be a struct")
+	}
+
+	return v.structSchema(typ, mt, map[reflect.Type]bool{})
+}
+
+// generate schema for a struct, based on its cached data
+func (v *validator) structSchema(
+	typ reflect.Type,
+	method methodType,
+	visited map[reflect.Type]bool,
+) (Schema, error) {
+	// avoid infinite recursion for self-referencing types
+	if visited[typ] {
+		return Schema{"type": "object"}, nil
+	}
+
+	visited[typ] = true
+	defer func() { visited[typ] = false }()
+
+	sd, ok := v.cache.get(typ)
+	if !ok {
+		var err error
+		sd, err = v.extractStructData(&fieldScope{typ: typ, value: reflect.New(typ).Elem()})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	properties := make(Schema, len(sd.fields))
+	required := []string{}
+
+	for _, fs := range sd.fields {
+		if fs == nil {
+			continue
+		}
+
+		fieldSchema, err := v.typeSchema(fs.typ, true, method, visited)
+		if err != nil {
+			return nil, err
+		}
+
+		// dive into slice/map elements
+		if fs.dive && (fs.kind == reflect.Slice || fs.kind == reflect.Array || fs.kind == reflect.Map) &&
+			!isBytes(fs.typ) {
+			elemSchema, err := v.typeSchema(derefType(fs.typ.Elem()), true, method, visited)
+			if err != nil {
+				return nil, err
+			}
+
+			if fs.kind == reflect.Map {
+				fieldSchema["additionalProperties"] = elemSchema
+			} else {
+				fieldSchema["items"] = elemSchema
+			}
+		}
+
+		isRequired := v.applySchemaRules(fs, fieldSchema, method)
+
+		// omitted empty fields are never validated
+		if fs.omitEmpty == all || fs.omitEmpty == method {
+			isRequired = false
+		}
+
+		// fields which can't be written during an update
+		if method == update && (fs.immutable || fs.createOnly) {
+			fieldSchema["readOnly"] = true
+			isRequired = false
+		}
+
+		properties[fs.jsonField] = fieldSchema
+
+		if isRequired {
+			required = append(required, fs.jsonField)
+		}
+	}
+
+	schema := Schema{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	if sd.name != "" {
+		schema["title"] = sd.name
+	}
+
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema, nil
+}
+
+// generate schema for a type, recursing into struct rules if validated
+func (v *validator) typeSchema(
+	typ reflect.Type,
+	validated bool,
+	method methodType,
+	visited map[reflect.Type]bool,
+) (Schema, error) {
+	typ = derefType(typ)
+
+	if typ == reflect.TypeOf(time.Time{}) {
+		return Schema{"type": "string", "format": "date-time"}, nil
+	}
+
+	if isBytes(typ) {
+		return Schema{"type": "string", "contentEncoding": "base64"}, nil
+	}
+
+	switch typ {
+	case docRefType:
+		return Schema{"type": "string", "description": "Document reference path"}, nil
+	case latLngType:
+		return Schema{
+			"type": "object",
+			"properties": Schema{
+				"latitude":  Schema{"type": "number"},
+				"longitude": Schema{"type": "number"},
+			},
+		}, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return Schema{"type": "string"}, nil
+	case reflect.Bool:
+		return Schema{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}, nil
+	case reflect.Struct:
+		if !validated {
+			return Schema{"type": "object"}, nil
+		}
+
+		return v.structSchema(typ, method, visited)
+	case reflect.Slice, reflect.Array:
+		items, err := v.typeSchema(typ.Elem(), false, method, visited)
+		if err != nil {
+			return nil, err
+		}
+
+		return Schema{"type": "array", "items": items}, nil
+	case reflect.Map:
+		props, err := v.typeSchema(typ.Elem(), false, method, visited)
+		if err != nil {
+			return nil, err
+		}
+
+		return Schema{"type": "object", "additionalProperties": props}, nil
+	default:
+		// interfaces accept any value
+		return Schema{}, nil
+	}
+}
+
+// apply field's rules to its schema and return whether it's required
+func (v *validator) applySchemaRules(fs *fieldScope, schema Schema, method methodType) bool {
+	var isRequired bool
+	var unmapped []string
+
+	for _, rule := range fs.rules {
+		// skip method specific rules which don't match current method
+		if rule.methodOnly != "" && rule.methodOnly != method {
+			continue
+		}
+
+		// transformations don't constrain the input
+		if rule.isTransform {
+			continue
+		}
+
+		if fn, ok := v.schemaRules[rule.name]; ok {
+			fn(rule.param, schema)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(rule.name, "required"):
+			isRequired = true
+		case rule.name == "min" || rule.name == "max":
+			if !v.applyBoundSchema(fs, schema, rule) {
+				unmapped = append(unmapped, rule.name)
+			}
+		case rule.name == "email":
+			schema["format"] = "email"
+		case rule.name == "latitude":
+			schema["minimum"], schema["maximum"] = -90, 90
+		case rule.name == "longitude":
+			schema["minimum"], schema["maximum"] = -180, 180
+		case rule.name == "geopoint" && derefType(fs.typ) == latLngType:
+			props := schema["properties"].(Schema)
+			props["latitude"].(Schema)["minimum"], props["latitude"].(Schema)["maximum"] = -90, 90
+			props["longitude"].(Schema)["minimum"], props["longitude"].(Schema)["maximum"] = -180, 180
+		case rule.name == "dimension":
+			if i, err := asInt(rule.param); err == nil {
+				schema["minItems"], schema["maxItems"] = i, i
+			} else {
+				unmapped = append(unmapped, rule.name)
+			}
+		case rule.name == "transitions":
+			schema["enum"] = v.transitionStates(rule.param)
+		default:
+			unmapped = append(unmapped, rule.name)
+		}
+	}
+
+	if len(unmapped) > 0 {
+		schema["x-firevault-rules"] = unmapped
+	}
+
+	return isRequired
+}
+
+// apply min/max rule to schema, based on field's kind
+func (v *validator) applyBoundSchema(fs *fieldScope, schema Schema, rule *ruleData) bool {
+	var keyword string
+
+	switch fs.kind {
+	case reflect.String:
+		// rule counts bytes, while minLength/maxLength count characters
+		return false
+	case reflect.Slice, reflect.Array:
+		if isBytes(fs.typ) {
+			return false
+		}
+
+		keyword = rule.name + "Items"
+	case reflect.Map:
+		keyword = rule.name + "Properties"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(rule.param, 64)
+		if err != nil {
+			return false
+		}
+
+		keyword = "minimum"
+		if rule.name == "max" {
+			keyword = "maximum"
+		}
+
+		schema[keyword] = f
+		return true
+	default:
+		return false
+	}
+
+	i, err := asInt(rule.param)
+	if err != nil {
+		return false
+	}
+
+	schema[keyword] = i
+	return true
+}
+
+// get all states of a transition graph, sorted
+func (v *validator) transitionStates(name string) []string {
+	states := []string{}
+
+	for from, tos := range v.transitions[name] {
+		for _, state := range append([]string{from}, tos...) {
+			if state != "" && !slices.Contains(states, state) {
+				states = append(states, state)
+			}
+		}
+	}
+
+	slices.Sort(states)
+	return states
+}
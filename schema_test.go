@@ -0,0 +1,120 @@
+package firevault
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJSONSchema(t *testing.T) {
+	type Address struct {
+		City string `firevault:"city,required" json:"city"`
+	}
+
+	type User struct {
+		Name      string             `firevault:"name,required,min=3,max=50,omitempty_update" json:"name"`
+		Email     string             `firevault:"email,required_create,email,is_unique" json:"email"`
+		Age       int                `firevault:"age,min=18" json:"age"`
+		Owner     string             `firevault:"owner,immutable" json:"owner"`
+		CreatedAt time.Time          `firevault:"created_at" json:"createdAt"`
+		Tags      []string           `firevault:"tags,max=5" json:"tags"`
+		Addresses []Address          `firevault:"addresses,dive" json:"addresses"`
+		Extra     map[string]Address `firevault:"extra,dive,custom_rule"`
+	}
+
+	connection := &Connection{Validator: NewValidator()}
+
+	// custom rules must be registered validations
+	for _, name := range []string{"is_unique", "custom_rule"} {
+		err := connection.RegisterValidation(name, ValidationFunc(func(fs FieldScope) (bool, error) {
+			return true, nil
+		}))
+		if err != nil {
+			t.Fatalf("Failed to register validation: %v", err)
+		}
+	}
+
+	err := connection.RegisterSchemaRule("custom_rule", func(param string, schema Schema) {
+		schema["minProperties"] = 1
+	})
+	if err != nil {
+		t.Fatalf("Failed to register schema rule: %v", err)
+	}
+
+	createSchema, err := JSONSchema[User](connection.Validator, "create")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if createSchema["$schema"] != jsonSchemaDialect {
+		t.Errorf("Unexpected $schema: %v", createSchema["$schema"])
+	}
+
+	wantRequired := []string{"name", "email"}
+	if !reflect.DeepEqual(createSchema["required"], wantRequired) {
+		t.Errorf("Create required = %v, want %v", createSchema["required"], wantRequired)
+	}
+
+	props := createSchema["properties"].(Schema)
+
+	tests := []struct {
+		name  string
+		field string
+		key   string
+		want  interface{}
+	}{
+		{"String length not mapped", "name", "x-firevault-rules", []string{"min", "max"}},
+		{"String min length", "name", "minLength", nil},
+		{"Email format", "email", "format", "email"},
+		{"Unmapped rule", "email", "x-firevault-rules", []string{"is_unique"}},
+		{"Number minimum", "age", "minimum", float64(18)},
+		{"Time format", "createdAt", "format", "date-time"},
+		{"Array max items", "tags", "maxItems", int64(5)},
+		{"Custom schema rule", "Extra", "minProperties", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := props[tt.field].(Schema)[tt.key]
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s.%s = %v, want %v", tt.field, tt.key, got, tt.want)
+			}
+		})
+	}
+
+	// dived elements use the nested struct's rules
+	items := props["addresses"].(Schema)["items"].(Schema)
+	if !reflect.DeepEqual(items["required"], []string{"city"}) {
+		t.Errorf("Unexpected dived items schema: %v", items)
+	}
+
+	schemas, err := OpenAPISchemas[User](connection.Validator)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	updateSchema, ok := schemas["UserUpdate"]
+	if !ok || schemas["UserCreate"] == nil {
+		t.Fatalf("Expected UserCreate and UserUpdate schemas, got %v", schemas)
+	}
+
+	if _, ok := updateSchema["required"]; ok {
+		t.Errorf("Expected no required fields during update, got %v", updateSchema["required"])
+	}
+
+	if updateSchema["properties"].(Schema)["owner"].(Schema)["readOnly"] != true {
+		t.Errorf("Expected immutable field to be read only during update")
+	}
+
+	// output must be serializable and deterministic
+	a, err := json.Marshal(updateSchema)
+	if err != nil {
+		t.Fatalf("Failed to marshal schema: %v", err)
+	}
+
+	b, _ := json.Marshal(updateSchema)
+	if string(a) != string(b) {
+		t.Errorf("Schema output is not deterministic")
+	}
+}
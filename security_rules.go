@@ -0,0 +1,494 @@
+package firevault
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// approximation of the email rule, in the rules language
+const rulesEmailPattern = `^[^@\\s]+@[^@\\s]+\\.[^@\\s]+$`
+
+// Generate a Firestore security rules match
+// block, enforcing the collection's struct tags
+// for direct client writes.
+//
+// The generated rules check field types, required
+// keys, allowed keys, string/list/map size bounds,
+// numeric bounds, transitions, as well as
+// immutable and createonly fields during updates.
+//
+// Rules which have no rules-language equivalent
+// (e.g. custom validations, transformations, or
+// rules on dived elements) are listed in comments
+// at the end of the block, so they can be
+// reviewed.
+//
+// The output is deterministic, following the
+// struct's field order.
+func (c *CollectionRef[T]) SecurityRules() (string, error) {
+	if c == nil {
+		return "", errors.New("firevault: nil CollectionRef")
+	}
+
+	return c.connection.validator.generateSecurityRules(reflect.TypeFor[T](), c.path)
+}
+
+// holds state while generating security rules
+type rulesBuilder struct {
+	v           *validator
+	unsupported []string
+}
+
+// generate security rules for a struct type and collection path
+func (v *validator) generateSecurityRules(typ reflect.Type, collPath string) (string, error) {
+	if v == nil {
+		return "", errors.New("firevault: nil validator")
+	}
+
+	typ = derefType(typ)
+	if typ.Kind() != reflect.Struct {
+		return "", errors.New("firevault: security rules type must be a struct")
+	}
+
+	rb := &rulesBuilder{v: v}
+
+	createConds, err := rb.structConditions(typ, create, "data", "", "", map[reflect.Type]bool{})
+	if err != nil {
+		return "", err
+	}
+
+	updateConds, err := rb.structConditions(typ, update, "data", "prev", "", map[reflect.Type]bool{})
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "// Generated by firevault from %s. DO NOT EDIT.\n", typ.Name())
+	fmt.Fprintf(&sb, "match /%s/{documentId} {\n", strings.Trim(collPath, "/"))
+
+	sb.WriteString("  function isValidCreate(data) {\n")
+	rb.writeConditions(&sb, createConds)
+	sb.WriteString("  }\n\n")
+
+	sb.WriteString("  function isValidUpdate(data, prev) {\n")
+	rb.writeConditions(&sb, updateConds)
+	sb.WriteString("  }\n\n")
+
+	sb.WriteString("  allow create: if isValidCreate(request.resource.data);\n")
+	sb.WriteString("  allow update: if isValidUpdate(request.resource.data, resource.data);\n")
+
+	if len(rb.unsupported) > 0 {
+		sb.WriteString("\n  // firevault: the following rules have no rules-language equivalent\n")
+		sb.WriteString("  // and are only enforced on the server:\n")
+
+		for _, note := range rb.unsupported {
+			fmt.Fprintf(&sb, "  //   %s\n", note)
+		}
+	}
+
+	sb.WriteString("}\n")
+	return sb.String(), nil
+}
+
+// write conditions as a function's return statement
+func (rb *rulesBuilder) writeConditions(sb *strings.Builder, conds []string) {
+	if len(conds) == 0 {
+		sb.WriteString("    return true;\n")
+		return
+	}
+
+	sb.WriteString("    return " + conds[0])
+
+	for _, cond := range conds[1:] {
+		sb.WriteString("\n      && " + cond)
+	}
+
+	sb.WriteString(";\n")
+}
+
+// record a rule without an equivalent
+func (rb *rulesBuilder) markUnsupported(path string, rule string, method methodType) {
+	note := fmt.Sprintf("%s: %s (%s)", path, rule, method)
+	if !slices.Contains(rb.unsupported, note) {
+		rb.unsupported = append(rb.unsupported, note)
+	}
+}
+
+// generate conditions for a struct's fields
+func (rb *rulesBuilder) structConditions(
+	typ reflect.Type,
+	method methodType,
+	data string,
+	prev string,
+	path string,
+	visited map[reflect.Type]bool,
+) ([]string, error) {
+	// avoid infinite recursion for self-referencing types
+	if visited[typ] {
+		return nil, nil
+	}
+
+	visited[typ] = true
+	defer func() { visited[typ] = false }()
+
+	sd, ok := rb.v.cache.get(typ)
+	if !ok {
+		var err error
+		sd, err = rb.v.extractStructData(&fieldScope{typ: typ, value: reflect.New(typ).Elem()})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var conds []string
+	var required []string
+	var allowed []string
+
+	for _, fs := range sd.fields {
+		if fs == nil {
+			continue
+		}
+
+		fieldPath := rb.v.getFieldPath(path, fs.field)
+		allowed = append(allowed, quoteRulesString(fs.field))
+
+		isRequired, fieldConds, err := rb.fieldConditions(fs, method, data, prev, fieldPath, visited)
+		if err != nil {
+			return nil, err
+		}
+
+		if isRequired {
+			required = append(required, quoteRulesString(fs.field))
+		}
+
+		if len(fieldConds) > 0 {
+			cond := strings.Join(fieldConds, " && ")
+
+			if !isRequired {
+				cond = fmt.Sprintf("(!(%s in %s) || (%s))", quoteRulesString(fs.field), data, cond)
+			}
+
+			conds = append(conds, cond)
+		}
+
+		// fields which can't be changed during an update
+		if prev != "" && fs.createOnly {
+			conds = append(conds, fmt.Sprintf(
+				"%s.get(%s, null) == %s.get(%s, null)",
+				data, quoteRulesString(fs.field), prev, quoteRulesString(fs.field),
+			))
+		} else if prev != "" && fs.immutable {
+			conds = append(conds, fmt.Sprintf(
+				"(!(%s in %s) || %s.get(%s, null) == %s)",
+				quoteRulesString(fs.field), prev, data, quoteRulesString(fs.field), rulesAccessor(prev, fs.field),
+			))
+		}
+	}
+
+	keyConds := []string{}
+
+	if len(required) > 0 {
+		keyConds = append(keyConds, fmt.Sprintf("%s.keys().hasAll([%s])", data, strings.Join(required, ", ")))
+	}
+
+	keyConds = append(keyConds, fmt.Sprintf("%s.keys().hasOnly([%s])", data, strings.Join(allowed, ", ")))
+
+	return append(keyConds, conds...), nil
+}
+
+// generate conditions for a single field and return whether it's required
+func (rb *rulesBuilder) fieldConditions(
+	fs *fieldScope,
+	method methodType,
+	data string,
+	prev string,
+	path string,
+	visited map[reflect.Type]bool,
+) (bool, []string, error) {
+	accessor := rulesAccessor(data, fs.field)
+
+	var conds []string
+	if typeCheck := rb.typeCheck(fs.typ); typeCheck != "" {
+		conds = append(conds, accessor+" is "+typeCheck)
+	}
+
+	var isRequired bool
+
+	for _, rule := range fs.rules {
+		// skip method specific rules which don't match current method
+		if rule.methodOnly != "" && rule.methodOnly != method {
+			continue
+		}
+
+		if rule.isTransform {
+			rb.markUnsupported(path, "transform:"+rule.name, method)
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(rule.name, "required"):
+			isRequired = true
+
+			if nonZero := rb.nonZeroCheck(fs.kind, accessor); nonZero != "" {
+				conds = append(conds, nonZero)
+			}
+		case rule.name == "min" || rule.name == "max":
+			bound := rb.boundCheck(fs, rule, accessor)
+			if bound == "" {
+				rb.markUnsupported(path, rule.name+"="+rule.param, method)
+				continue
+			}
+
+			conds = append(conds, bound)
+		case rule.name == "email":
+			conds = append(conds, fmt.Sprintf("%s.matches('%s')", accessor, rulesEmailPattern))
+			rb.markUnsupported(path, "email (approximated by a pattern)", method)
+		case rule.name == "transitions":
+			conds = append(conds, rb.transitionCheck(fs, rule.param, data, prev))
+		case rule.name == "latitude":
+			conds = append(conds, fmt.Sprintf("%s >= -90 && %s <= 90", accessor, accessor))
+		case rule.name == "longitude":
+			conds = append(conds, fmt.Sprintf("%s >= -180 && %s <= 180", accessor, accessor))
+		case rule.name == "geopoint":
+			conds = append(conds, fmt.Sprintf(
+				"%s.latitude() >= -90 && %s.latitude() <= 90 && %s.longitude() >= -180 && %s.longitude() <= 180",
+				accessor, accessor, accessor, accessor,
+			))
+		case rule.name == "max_bytes" && isBytes(fs.typ):
+			i, err := asInt(rule.param)
+			if err != nil {
+				rb.markUnsupported(path, rule.name+"="+rule.param, method)
+				continue
+			}
+
+			conds = append(conds, fmt.Sprintf("%s.size() <= %d", accessor, i))
+		default:
+			rb.markUnsupported(path, rule.name, method)
+		}
+	}
+
+	// omitted empty fields are never validated
+	if fs.omitEmpty == all || fs.omitEmpty == method {
+		isRequired = false
+	}
+
+	// nested structs are validated recursively
+	if fs.kind == reflect.Struct && fs.typ != reflect.TypeOf(time.Time{}) && !rb.v.isSpecialType(fs.typ) {
+		nestedPrev := ""
+		if prev != "" {
+			nestedPrev = fmt.Sprintf("%s.get(%s, {})", prev, quoteRulesString(fs.field))
+		}
+
+		nested, err := rb.structConditions(fs.typ, method, accessor, nestedPrev, path, visited)
+		if err != nil {
+			return false, nil, err
+		}
+
+		conds = append(conds, nested...)
+	}
+
+	// elements can't be iterated in the rules language
+	if fs.dive && (fs.kind == reflect.Slice || fs.kind == reflect.Array || fs.kind == reflect.Map) &&
+		rb.v.usesAnyRule(derefType(fs.typ.Elem())) {
+		rb.markUnsupported(path, "dive", method)
+	}
+
+	return isRequired, conds, nil
+}
+
+// get the rules-language type check for a type
+func (rb *rulesBuilder) typeCheck(typ reflect.Type) string {
+	if typ == reflect.TypeOf(time.Time{}) {
+		return "timestamp"
+	}
+
+	if isBytes(typ) {
+		return "bytes"
+	}
+
+	switch typ {
+	case docRefType:
+		return "path"
+	case latLngType:
+		return "latlng"
+	case vector32Type, vector64Type:
+		// vectors are stored as maps
+		return "map"
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Struct, reflect.Map:
+		return "map"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	default:
+		return ""
+	}
+}
+
+// get the rules-language check equivalent to the required rule
+func (rb *rulesBuilder) nonZeroCheck(kind reflect.Kind, accessor string) string {
+	switch kind {
+	case reflect.String:
+		return accessor + " != ''"
+	case reflect.Bool:
+		return accessor + " == true"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return accessor + " != 0"
+	default:
+		return ""
+	}
+}
+
+// get the rules-language check equivalent to a min/max rule
+func (rb *rulesBuilder) boundCheck(fs *fieldScope, rule *ruleData, accessor string) string {
+	operator := ">="
+	if rule.name == "max" {
+		operator = "<="
+	}
+
+	switch fs.kind {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		i, err := asInt(rule.param)
+		if err != nil {
+			return ""
+		}
+
+		return fmt.Sprintf("%s.size() %s %d", accessor, operator, i)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(rule.param, 64)
+		if err != nil {
+			return ""
+		}
+
+		return fmt.Sprintf("%s %s %s", accessor, operator, strconv.FormatFloat(f, 'f', -1, 64))
+	default:
+		return ""
+	}
+}
+
+// get the rules-language check equivalent to a transitions rule
+func (rb *rulesBuilder) transitionCheck(fs *fieldScope, name string, data string, prev string) string {
+	graph := rb.v.transitions[name]
+	accessor := rulesAccessor(data, fs.field)
+	key := quoteRulesString(fs.field)
+
+	// sort states, for deterministic output
+	states := make([]string, 0, len(graph))
+	for from := range graph {
+		states = append(states, from)
+	}
+	slices.Sort(states)
+
+	var initial string
+	if tos, ok := graph[""]; ok {
+		initial = fmt.Sprintf("%s in [%s]", accessor, quoteRulesList(tos))
+	}
+
+	if prev == "" {
+		if initial == "" {
+			return "true"
+		}
+
+		return initial
+	}
+
+	// unchanged, or not previously stored
+	notStored := fmt.Sprintf("!(%s in %s)", key, prev)
+	if initial != "" {
+		notStored = fmt.Sprintf("(%s && %s)", notStored, initial)
+	}
+
+	alternatives := []string{
+		fmt.Sprintf("%s == %s.get(%s, null)", accessor, prev, key),
+		notStored,
+	}
+
+	for _, from := range states {
+		if from == "" {
+			continue
+		}
+
+		alternatives = append(alternatives, fmt.Sprintf(
+			"(%s.get(%s, null) == %s && %s in [%s])",
+			prev, key, quoteRulesString(from), accessor, quoteRulesList(graph[from]),
+		))
+	}
+
+	return "(" + strings.Join(alternatives, " || ") + ")"
+}
+
+// check if struct type uses any validation rules
+func (v *validator) usesAnyRule(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		// inlined struct's fields are part of this struct
+		if v.isInlineField(typ.Field(i)) {
+			if inlineTyp := derefType(typ.Field(i).Type); inlineTyp != typ && v.usesAnyRule(inlineTyp) {
+				return true
+			}
+
+			continue
+		}
+
+		tag := typ.Field(i).Tag.Get("firevault")
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		if len(v.cleanRules(v.parseTag(tag))) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// get the rules-language accessor of a map's field
+func rulesAccessor(data string, field string) string {
+	if rulesIdentifierRegex().MatchString(field) {
+		return data + "." + field
+	}
+
+	return data + "[" + quoteRulesString(field) + "]"
+}
+
+// quote a string for the rules language
+func quoteRulesString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+
+	return "'" + s + "'"
+}
+
+// quote a list of strings for the rules language
+func quoteRulesList(list []string) string {
+	quoted := make([]string, len(list))
+	for i, s := range list {
+		quoted[i] = quoteRulesString(s)
+	}
+
+	return strings.Join(quoted, ", ")
+}
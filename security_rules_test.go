@@ -0,0 +1,65 @@
+package firevault
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSecurityRules(t *testing.T) {
+	type Address struct {
+		City string `firevault:"city,required,max=20"`
+	}
+
+	type User struct {
+		Name    string   `firevault:"name,required,min=3,omitempty_update"`
+		Email   string   `firevault:"email,required_create,is_unique"`
+		Age     int      `firevault:"age,min=18"`
+		Owner   string   `firevault:"owner,immutable"`
+		Creator string   `firevault:"created-by,createonly"`
+		Status  string   `firevault:"status,transitions=status"`
+		Address *Address `firevault:"address,omitempty"`
+	}
+
+	v := newValidator()
+	_ = v.registerValidation("is_unique", func(ctx context.Context, tx *Transaction, fs FieldScope) (bool, error) {
+		return true, nil
+	}, false, false)
+	_ = v.registerTransitions("status", map[string][]string{"open": {"closed"}})
+
+	rules, err := v.generateSecurityRules(reflect.TypeOf(User{}), "users")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Match block", "match /users/{documentId} {"},
+		{"Required keys", "data.keys().hasAll(['name', 'email'])"},
+		{"Allowed keys", "data.keys().hasOnly(['name', 'email', 'age', 'owner', 'created-by', 'status', 'address'])"},
+		{"String bounds", "data.name is string && data.name != '' && data.name.size() >= 3"},
+		{"Optional number", "(!('age' in data) || (data.age is int && data.age >= 18))"},
+		{"Immutable field", "(!('owner' in prev) || data.get('owner', null) == prev.owner)"},
+		{"Createonly field", "data.get('created-by', null) == prev.get('created-by', null)"},
+		{"Quoted field", "data['created-by'] is string"},
+		{"Transition", "(prev.get('status', null) == 'open' && data.status in ['closed'])"},
+		{"Nested struct", "data.address.city.size() <= 20"},
+		{"Unsupported rule", "//   email: is_unique (create)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(rules, tt.want) {
+				t.Errorf("Expected rules to contain %q, got:\n%s", tt.want, rules)
+			}
+		})
+	}
+
+	again, _ := v.generateSecurityRules(reflect.TypeOf(User{}), "users")
+	if again != rules {
+		t.Errorf("Security rules output is not deterministic")
+	}
+}
@@ -0,0 +1,99 @@
+package firevault
+
+import (
+	"context"
+	"errors"
+)
+
+// SetResult reports which operation Set performed.
+type SetResult int
+
+const (
+	// The document didn't exist, so it was created.
+	SetCreated SetResult = iota + 1
+	// The document existed, so it was updated.
+	SetUpdated
+)
+
+// Create or update a Firestore document with the
+// provided ID (after data validation), depending on
+// whether it exists.
+//
+// If the document doesn't exist, it's created, applying
+// the same rules as Create (e.g. required_create).
+// Otherwise, it's updated, applying the same rules (and
+// Options) as Update (e.g. omitempty_update), merging
+// data fields by default. To replace the existing
+// document instead, use the ReplaceAll option.
+//
+// A precondition set via Options (e.g. using
+// RequireLastUpdateTime) requires the document to
+// exist. If it doesn't, it's not created, and
+// ErrPreconditionFailed is returned.
+//
+// The document is read and written within a
+// transaction, so the decision is atomic. If no
+// transaction is passed via Options, a new one is run
+// (and retried in case of contention).
+//
+// Returns whether the document was created or updated.
+func (c *CollectionRef[T]) Set(ctx context.Context, docID string, data *T, opts ...Options) (SetResult, error) {
+	if c == nil {
+		return 0, errors.New("firevault: nil CollectionRef")
+	}
+
+	if !isValidID(docID) {
+		return 0, errors.New("firevault: document ID cannot be empty or contain a slash")
+	}
+
+	options := NewOptions()
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if options.transaction != nil {
+		return c.set(ctx, docID, data, options)
+	}
+
+	var result SetResult
+
+	err := c.connection.RunTransaction(ctx, func(ctx context.Context, tx *Transaction) error {
+		var err error
+		result, err = c.set(ctx, docID, data, options.Transaction(tx))
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return result, nil
+}
+
+// create or update a document, within the transaction of provided options
+func (c *CollectionRef[T]) set(ctx context.Context, docID string, data *T, options Options) (SetResult, error) {
+	snapshots, err := c.fetchSnapshotsByID(ctx, options.transaction, []string{docID})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(snapshots) == 0 {
+		// preconditions (e.g. a last update time) require an existing document
+		if options.precondition != nil {
+			return 0, &DocError{docID, ErrPreconditionFailed}
+		}
+
+		_, err = c.Create(ctx, data, options.CustomID(docID))
+		if err != nil {
+			return 0, err
+		}
+
+		return SetCreated, nil
+	}
+
+	err = c.Update(ctx, Query{ids: []string{docID}}, data, options)
+	if err != nil {
+		return 0, err
+	}
+
+	return SetUpdated, nil
+}
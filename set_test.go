@@ -0,0 +1,260 @@
+package firevault
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	pb "cloud.google.com/go/firestore/apiv1/firestorepb"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// an in-memory Firestore server, supporting the reads and writes of transactions
+type fakeFirestore struct {
+	pb.UnimplementedFirestoreServer
+	mu     sync.Mutex
+	docs   map[string]*pb.Document
+	writes []*pb.Write
+}
+
+func (f *fakeFirestore) BatchGetDocuments(
+	req *pb.BatchGetDocumentsRequest,
+	stream pb.Firestore_BatchGetDocumentsServer,
+) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, name := range req.Documents {
+		resp := &pb.BatchGetDocumentsResponse{
+			Result:   &pb.BatchGetDocumentsResponse_Missing{Missing: name},
+			ReadTime: timestamppb.Now(),
+		}
+
+		if doc, ok := f.docs[name]; ok {
+			resp.Result = &pb.BatchGetDocumentsResponse_Found{Found: doc}
+		}
+
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeFirestore) BeginTransaction(
+	context.Context,
+	*pb.BeginTransactionRequest,
+) (*pb.BeginTransactionResponse, error) {
+	return &pb.BeginTransactionResponse{Transaction: []byte("tx")}, nil
+}
+
+func (f *fakeFirestore) Commit(_ context.Context, req *pb.CommitRequest) (*pb.CommitResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := timestamppb.Now()
+	resp := &pb.CommitResponse{CommitTime: now}
+
+	for _, write := range req.Writes {
+		doc := write.GetUpdate()
+		if doc == nil {
+			return nil, status.Error(codes.Unimplemented, "only updates are supported")
+		}
+
+		_, exists := f.docs[doc.Name]
+		if precond := write.GetCurrentDocument(); precond != nil && precond.GetExists() != exists {
+			if exists {
+				return nil, status.Error(codes.AlreadyExists, "document already exists")
+			}
+
+			return nil, status.Error(codes.NotFound, "no document to update")
+		}
+
+		f.writes = append(f.writes, write)
+		f.docs[doc.Name] = &pb.Document{Name: doc.Name, Fields: doc.Fields, CreateTime: now, UpdateTime: now}
+		resp.WriteResults = append(resp.WriteResults, &pb.WriteResult{UpdateTime: now})
+	}
+
+	return resp, nil
+}
+
+func (f *fakeFirestore) Rollback(context.Context, *pb.RollbackRequest) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
+// start a fake Firestore server, returning a Connection to it
+func newFakeConnection(t *testing.T, fake *fakeFirestore) *Connection {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	srv := grpc.NewServer()
+	pb.RegisterFirestoreServer(srv, fake)
+
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial fake server: %v", err)
+	}
+
+	client, err := firestore.NewClient(context.Background(), "test-project", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return &Connection{Validator: NewValidator(), client: client}
+}
+
+func TestSet(t *testing.T) {
+	var nilCollection *CollectionRef[map[string]interface{}]
+	if _, err := nilCollection.Set(context.Background(), "u1", &map[string]interface{}{}); err == nil {
+		t.Errorf("Expected error for nil CollectionRef")
+	}
+
+	collection := &CollectionRef[map[string]interface{}]{
+		connection: &Connection{Validator: NewValidator()},
+		path:       "users",
+	}
+
+	for _, docID := range []string{"", "u1/posts/p1"} {
+		result, err := collection.Set(context.Background(), docID, &map[string]interface{}{})
+		if err == nil || result != 0 {
+			t.Errorf("Expected error and no result for document ID %q, got %v", docID, result)
+		}
+	}
+}
+
+func TestSetRules(t *testing.T) {
+	type User struct {
+		Name  string `firevault:"name,required_create"`
+		Email string `firevault:"email,omitempty_update"`
+		Age   int    `firevault:"age,omitempty"`
+	}
+
+	const existingName = "projects/test-project/databases/(default)/documents/users/u1"
+
+	fake := &fakeFirestore{docs: map[string]*pb.Document{
+		existingName: {
+			Name:       existingName,
+			Fields:     map[string]*pb.Value{"name": {ValueType: &pb.Value_StringValue{StringValue: "john"}}},
+			CreateTime: timestamppb.Now(),
+			UpdateTime: timestamppb.Now(),
+		},
+	}}
+
+	users := Collection[User](newFakeConnection(t, fake), "users")
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		docID      string
+		data       *User
+		opts       []Options
+		wantResult SetResult
+		wantRule   string
+		wantErr    error
+		wantFields []string
+		wantExists bool
+	}{
+		{
+			name:     "Create rules applied to missing document",
+			docID:    "u2",
+			data:     &User{Email: "jane@example.com"},
+			wantRule: "required_create",
+		},
+		{
+			name:       "Missing document created",
+			docID:      "u2",
+			data:       &User{Name: "jane"},
+			wantResult: SetCreated,
+			wantFields: []string{"email", "name"},
+		},
+		{
+			name:       "Update rules applied to existing document",
+			docID:      "u1",
+			data:       &User{Age: 30},
+			wantResult: SetUpdated,
+			wantFields: []string{"age", "name"},
+			wantExists: true,
+		},
+		{
+			name:    "Precondition of missing document",
+			docID:   "u3",
+			data:    &User{Name: "jim"},
+			opts:    []Options{NewOptions().RequireLastUpdateTime(time.Now().Truncate(time.Microsecond))},
+			wantErr: ErrPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.mu.Lock()
+			fake.writes = nil
+			fake.mu.Unlock()
+
+			result, err := users.Set(ctx, tt.docID, tt.data, tt.opts...)
+
+			if tt.wantRule != "" || tt.wantErr != nil {
+				var fe FieldError
+				if tt.wantRule != "" && (!errors.As(err, &fe) || fe.Rule() != tt.wantRule) {
+					t.Errorf("Expected FieldError on %s rule, got %v", tt.wantRule, err)
+				}
+
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("Set() error = %v, want %v", err, tt.wantErr)
+				}
+
+				if result != 0 || len(fake.writes) != 0 {
+					t.Errorf("Expected no result or writes, got %v and %v", result, fake.writes)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if result != tt.wantResult {
+				t.Errorf("Set() = %v, want %v", result, tt.wantResult)
+			}
+
+			if len(fake.writes) != 1 {
+				t.Fatalf("Expected a single write, got %v", fake.writes)
+			}
+
+			write := fake.writes[0]
+
+			var fields []string
+			for field := range write.GetUpdate().Fields {
+				fields = append(fields, field)
+			}
+			slices.Sort(fields)
+
+			if !slices.Equal(fields, tt.wantFields) {
+				t.Errorf("Written fields = %v, want %v", fields, tt.wantFields)
+			}
+
+			if write.GetCurrentDocument().GetExists() != tt.wantExists {
+				t.Errorf("Write precondition exists = %v, want %v", write.GetCurrentDocument().GetExists(), tt.wantExists)
+			}
+		})
+	}
+}
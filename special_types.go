@@ -0,0 +1,91 @@
+package firevault
+
+import (
+	"reflect"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// Firestore's special types, which are stored as they are
+var (
+	docRefType   = reflect.TypeFor[firestore.DocumentRef]()
+	latLngType   = reflect.TypeFor[latlng.LatLng]()
+	vector32Type = reflect.TypeFor[firestore.Vector32]()
+	vector64Type = reflect.TypeFor[firestore.Vector64]()
+)
+
+// check if (dereferenced) type is one of Firestore's special types, which are never recursed into
+func (v *validator) isSpecialType(typ reflect.Type) bool {
+	switch typ {
+	case docRefType, latLngType, vector32Type, vector64Type:
+		return true
+	}
+
+	return isBytes(typ)
+}
+
+// get the value of a special type, as expected by Firestore
+func (v *validator) specialValue(val reflect.Value) interface{} {
+	switch val.Type() {
+	case docRefType, latLngType:
+		// references and geopoints are only accepted as pointers
+		return addressable(val).Addr().Interface()
+	case vector32Type, vector64Type:
+		return val.Interface()
+	}
+
+	// named byte slices aren't accepted
+	return val.Bytes()
+}
+
+// get a (possibly copied) addressable value
+func addressable(val reflect.Value) reflect.Value {
+	if val.CanAddr() {
+		return val
+	}
+
+	addressable := reflect.New(val.Type()).Elem()
+	addressable.Set(val)
+
+	return addressable
+}
+
+// get the geopoint held by a value (without copying it)
+func asLatLng(val reflect.Value) *latlng.LatLng {
+	return addressable(val).Addr().Interface().(*latlng.LatLng)
+}
+
+// get the reference held by a value
+func asDocRef(val reflect.Value) *firestore.DocumentRef {
+	return addressable(val).Addr().Interface().(*firestore.DocumentRef)
+}
+
+// decode a Firestore value into a special type, reporting whether it is one
+func (v *validator) decodeSpecial(dst reflect.Value, src reflect.Value) bool {
+	switch {
+	case (dst.Type() == docRefType || dst.Type() == latLngType) &&
+		src.Kind() == reflect.Pointer && src.Type().Elem() == dst.Type():
+		// references and geopoints are returned as pointers
+		if !src.IsNil() {
+			dst.Set(src.Elem())
+		}
+
+		return true
+	case src.Type() == vector64Type && dst.Kind() == reflect.Slice &&
+		(dst.Type().Elem().Kind() == reflect.Float32 || dst.Type().Elem().Kind() == reflect.Float64):
+		// vectors are returned as Vector64
+		vector := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			vector.Index(i).SetFloat(src.Index(i).Float())
+		}
+
+		dst.Set(vector)
+		return true
+	case isBytes(src.Type()) && isBytes(dst.Type()):
+		dst.Set(src.Convert(dst.Type()))
+		return true
+	}
+
+	return false
+}
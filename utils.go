@@ -2,6 +2,7 @@ package firevault
 
 import (
 	"errors"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -51,3 +52,17 @@ func asTime(param string) (time.Time, error) {
 
 	return t, nil
 }
+
+// asNumber returns the numeric value as a float64, or false if it is not a number
+func asNumber(val reflect.Value) (float64, bool) {
+	switch {
+	case val.CanInt():
+		return float64(val.Int()), true
+	case val.CanUint():
+		return float64(val.Uint()), true
+	case val.CanFloat():
+		return val.Float(), true
+	default:
+		return 0, false
+	}
+}
@@ -7,22 +7,46 @@ import (
 	"reflect"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
 type validator struct {
-	validations     map[string]valFnWrapper
-	transformations map[string]transFnWrapper
-	errFormatters   []ErrorFormatterFunc
-	cache           *structCache
+	validations       map[string]valFnWrapper
+	transformations   map[string]transFnWrapper
+	errFormatters     []ErrorFormatterFunc
+	ruleErrFormatters map[string]ErrorFormatterFunc // used if no registered formatter returns an error
+	transitions       map[string]map[string][]string
+	schemaRules       map[string]SchemaRuleFunc
+	cache             *structCache
+	ruleUsage         sync.Map // map[ruleUsageKey]bool
+	generated         map[reflect.Type]generatedFuncInternal
+	generatedFields   sync.Map // map[*GeneratedField]*fieldScope
+	types             map[string]reflect.Type
+	typeKeys          map[reflect.Type]string
+	decoderUsage      sync.Map // map[reflect.Type]bool
+	customTypes       map[reflect.Type]*customType
+}
+
+// used to cache whether a struct type uses a rule
+type ruleUsageKey struct {
+	typ  reflect.Type
+	rule string
 }
 
 func newValidator() *validator {
 	validator := &validator{
-		make(map[string]valFnWrapper, len(builtInValidators)),
-		make(map[string]transFnWrapper, len(builtInTransformators)),
-		make([]ErrorFormatterFunc, 0),
-		&structCache{},
+		validations:       make(map[string]valFnWrapper, len(builtInValidators)),
+		transformations:   make(map[string]transFnWrapper, len(builtInTransformators)),
+		errFormatters:     make([]ErrorFormatterFunc, 0),
+		ruleErrFormatters: make(map[string]ErrorFormatterFunc),
+		transitions:       make(map[string]map[string][]string),
+		schemaRules:       make(map[string]SchemaRuleFunc),
+		cache:             &structCache{},
+		generated:         make(map[reflect.Type]generatedFuncInternal),
+		types:             make(map[string]reflect.Type),
+		typeKeys:          make(map[reflect.Type]string),
+		customTypes:       make(map[reflect.Type]*customType),
 	}
 
 	// register predefined validators
@@ -37,6 +61,10 @@ func newValidator() *validator {
 		_ = validator.registerValidation(name, val.toValFuncInternal(), true, runOnNil)
 	}
 
+	// register transitions validator (needs access to registered graphs)
+	_ = validator.registerValidation("transitions", validator.validateTransition, true, false)
+	validator.ruleErrFormatters["transitions"] = validator.formatTransitionErr
+
 	// register predefined transformators
 	for name, trans := range builtInTransformators {
 		// no need to error check here, built in validations are always valid
@@ -51,8 +79,9 @@ type valFuncInternal func(ctx context.Context, tx *Transaction, fs FieldScope) (
 
 // holds val func as well as whether it can be called on nil values
 type valFnWrapper struct {
-	fn       valFuncInternal
-	runOnNil bool
+	fn         valFuncInternal
+	runOnNil   bool
+	marshalled bool
 }
 
 // register a validation
@@ -83,7 +112,10 @@ func (v *validator) registerValidation(
 		return fmt.Errorf("firevault: validation function %s cannot be empty", name)
 	}
 
-	v.validations[name] = valFnWrapper{validation, runOnNil}
+	// built-in validations (apart from required) check the Firestore values of custom types
+	marshalled := builtIn && !strings.Contains(name, "required")
+
+	v.validations[name] = valFnWrapper{validation, runOnNil, marshalled}
 	return nil
 }
 
@@ -150,6 +182,164 @@ func (v *validator) registerErrorFormatter(errFormatter ErrorFormatterFunc) erro
 	return nil
 }
 
+// register a transition graph
+func (v *validator) registerTransitions(name string, transitions map[string][]string) error {
+	if v == nil {
+		return errors.New("firevault: nil validator")
+	}
+
+	if len(name) == 0 || strings.ContainsAny(name, restrictedTagChars) {
+		return errors.New("firevault: transitions name cannot be empty or contain restricted characters")
+	}
+
+	if len(transitions) == 0 {
+		return fmt.Errorf("firevault: transitions %s cannot be empty", name)
+	}
+
+	v.transitions[name] = transitions
+	return nil
+}
+
+// validates if field's value change is an allowed transition
+func (v *validator) validateTransition(_ context.Context, _ *Transaction, fs FieldScope) (bool, error) {
+	graph, ok := v.transitions[fs.Param()]
+	if !ok {
+		return false, errors.New("firevault: unknown transitions param - " + fs.Path())
+	}
+
+	// stored values aren't marshalled, so both sides are normalised in the same way
+	from, err := v.transitionState(fs.Previous())
+	if err != nil {
+		return false, err
+	}
+
+	to, err := v.transitionState(fs.Value())
+	if err != nil {
+		return false, err
+	}
+
+	if from == to {
+		return true, nil
+	}
+
+	allowed, ok := graph[from]
+	if !ok {
+		// any initial state is allowed, unless specified
+		return from == "", nil
+	}
+
+	return slices.Contains(allowed, to), nil
+}
+
+// get state of a transition from a value, i.e. its (marshalled) string form
+func (v *validator) transitionState(val reflect.Value) (string, error) {
+	for val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return "", nil
+		}
+
+		if v.isValuer(val.Type()) {
+			break
+		}
+
+		val = val.Elem()
+	}
+
+	if marshalled, ok, err := v.marshalValue(val); ok {
+		if err != nil {
+			return "", err
+		}
+
+		val = reflect.ValueOf(marshalled)
+	}
+
+	if !val.IsValid() || val.IsZero() {
+		return "", nil
+	}
+
+	return fmt.Sprint(val.Interface()), nil
+}
+
+// describe the attempted transition in the error message (keeping the FieldError wrapped)
+func (v *validator) formatTransitionErr(fe FieldError) error {
+	var from string
+	if tfe, ok := fe.(TransitionFieldError); ok {
+		from, _ = v.transitionState(tfe.Previous())
+	}
+
+	to, _ := v.transitionState(fe.Value())
+
+	return fmt.Errorf("%w (transition from '%s' to '%s' is not allowed)", fe, from, to)
+}
+
+// check if struct type (or any nested type) uses a rule
+func (v *validator) usesRule(typ reflect.Type, rule string) bool {
+	key := ruleUsageKey{typ, rule}
+
+	if used, ok := v.ruleUsage.Load(key); ok {
+		return used.(bool)
+	}
+
+	used := v.typeUsesRule(typ, rule, map[reflect.Type]bool{})
+	v.ruleUsage.Store(key, used)
+
+	return used
+}
+
+// recursively check if struct type uses a rule
+func (v *validator) typeUsesRule(typ reflect.Type, rule string, visited map[reflect.Type]bool) bool {
+	for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice ||
+		typ.Kind() == reflect.Array || typ.Kind() == reflect.Map {
+		typ = typ.Elem()
+	}
+
+	// check registered types which can be held by interface
+	if typ.Kind() == reflect.Interface {
+		for _, t := range v.types {
+			if (t.Implements(typ) || reflect.PointerTo(t).Implements(typ)) && v.typeUsesRule(t, rule, visited) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if typ.Kind() != reflect.Struct || visited[typ] {
+		return false
+	}
+
+	visited[typ] = true
+
+	for i := 0; i < typ.NumField(); i++ {
+		// inlined struct's fields are part of this struct
+		if v.isInlineField(typ.Field(i)) {
+			if v.typeUsesRule(typ.Field(i).Type, rule, visited) {
+				return true
+			}
+
+			continue
+		}
+
+		tag := typ.Field(i).Tag.Get("firevault")
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		for _, r := range v.parseTag(tag)[1:] {
+			name, _, _ := strings.Cut(r, "=")
+			if name == rule {
+				return true
+			}
+		}
+
+		if v.typeUsesRule(typ.Field(i).Type, rule, visited) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // options used during validation
 type validationOpts struct {
 	collPath           string
@@ -160,7 +350,12 @@ type validationOpts struct {
 	emptyFieldsAllowed []string
 	modifyOriginal     bool
 	deleteEmpty        bool
+	rejectImmutable    bool
 	tx                 *Transaction
+	docIDs             []string
+	options            Options
+	root               reflect.Value
+	previous           reflect.Value
 }
 
 // check if passed data is a struct pointer and reflect it if so
@@ -186,11 +381,46 @@ func (v *validator) validate(
 	fs.typ = fs.typ.Elem()
 	fs.value = fs.value.Elem()
 
+	// use concrete type of interface (e.g. when using CollectionRef with an interface type)
+	var typeKey string
+	if fs.value.Kind() == reflect.Interface {
+		fs.value, typeKey = v.concreteValue(fs.value)
+		if typeKey == "" {
+			return nil, errors.New("firevault: data must hold a registered type")
+		}
+
+		fs.typ = fs.value.Type()
+		data = fs.value.Addr().Interface()
+		opts.previous, _ = v.concreteValue(opts.previous)
+	}
+
 	if fs.value.Kind() != reflect.Struct {
 		return nil, errors.New("firevault: data must be a pointer to a struct")
 	}
 
-	dataMap, err := v.validateStructFields(ctx, fs, opts)
+	// shared by all field scopes during this validation
+	opts.root = fs.value
+	fs.opts = &opts
+
+	// previously stored document (must be of the same type)
+	if opts.previous.IsValid() && opts.previous.Type() == fs.typ {
+		fs.prev = opts.previous
+	}
+
+	var dataMap map[string]interface{}
+	var err error
+
+	// use generated function, if registered (and stored values aren't needed)
+	if gen, ok := v.generated[fs.typ]; ok && !fs.prev.IsValid() {
+		dataMap, err = gen(&GeneratedScope{ctx: ctx, v: v, opts: &opts, fs: fs, data: data}, data)
+	} else {
+		dataMap, err = v.validateStructFields(ctx, fs, opts)
+	}
+
+	if err == nil && typeKey != "" {
+		dataMap[DiscriminatorField] = typeKey
+	}
+
 	return dataMap, err
 }
 
@@ -201,9 +431,6 @@ func (v *validator) validateStructFields(
 	parentFs *fieldScope,
 	opts validationOpts,
 ) (map[string]interface{}, error) {
-	// map which will hold all fields to pass to firestore
-	dataMap := make(map[string]interface{}, parentFs.value.NumField())
-
 	// get cached struct data, if available
 	sd, ok := v.cache.get(parentFs.typ)
 	if !ok {
@@ -215,25 +442,35 @@ func (v *validator) validateStructFields(
 		}
 	}
 
+	// map which will hold all fields to pass to firestore
+	dataMap := make(map[string]interface{}, len(sd.fields))
+
 	// iterate over struct fields
 	for i := 0; i < len(sd.fields); i++ {
-		cachedFs := sd.fields[i]
-		newVal := parentFs.value.Field(i)
+		// copy cached field data, as the scope holds the state of this validation
+		fs := *sd.fields[i]
+
+		fs.value = v.fieldByIndex(parentFs.value, fs.index)
+		fs.strct = parentFs.value
+		fs.parent = parentFs
+		fs.opts = parentFs.opts
 
-		// always use latest value
-		if cachedFs.value != newVal {
-			cachedFs.value = newVal
+		// use previously stored value, if available
+		if parentFs.prev.IsValid() {
+			fs.prev = v.fieldByIndex(parentFs.prev, fs.index)
 		}
 
 		// use dynamic paths (of map/slice element as its key/index may have changed)
-		if cachedFs.dynamic {
-			cachedFs.path = v.getFieldPath(parentFs.path, cachedFs.field)
-			cachedFs.structField = v.getFieldPath(parentFs.structPath, cachedFs.structField)
+		if fs.dynamic || parentFs.dynamic {
+			fs.path = v.getFieldPath(parentFs.path, fs.field)
+			fs.structPath = v.getFieldPath(parentFs.structPath, fs.structField)
+			fs.jsonPath = v.getFieldPath(parentFs.jsonPath, fs.jsonField)
+			fs.jsonPointer = v.getJSONPointer(parentFs.jsonPointer, fs.jsonField)
 		}
 
 		// process each individual field
 		// (has side effects as it updates original struct after transformation (if allowed))
-		fieldName, fieldValue, err := v.processStructField(ctx, cachedFs, opts)
+		fieldName, fieldValue, err := v.processStructField(ctx, &fs, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -250,12 +487,44 @@ func (v *validator) validateStructFields(
 func (v *validator) extractStructData(parentFs *fieldScope) (*structData, error) {
 	sd := &structData{
 		name:   parentFs.typ.Name(),
-		fields: make([]*fieldScope, parentFs.typ.NumField()),
+		fields: make([]*fieldScope, 0, parentFs.typ.NumField()),
+	}
+
+	// used to detect conflicting field names (e.g. of inlined structs)
+	names := make(map[string]string, parentFs.typ.NumField())
+
+	err := v.extractFields(sd, parentFs, parentFs, nil, names, map[reflect.Type]bool{parentFs.typ: true})
+	if err != nil {
+		return nil, err
 	}
 
-	for i := 0; i < parentFs.typ.NumField(); i++ {
-		fieldValue := parentFs.value.Field(i)
-		fieldType := parentFs.typ.Field(i)
+	// store in cache
+	v.cache.set(parentFs.typ, sd)
+	return sd, nil
+}
+
+// collect fields data of a struct (or of a struct inlined into it)
+func (v *validator) extractFields(
+	sd *structData,
+	parentFs *fieldScope,
+	containerFs *fieldScope,
+	index []int,
+	names map[string]string,
+	inlined map[reflect.Type]bool,
+) error {
+	for i := 0; i < containerFs.typ.NumField(); i++ {
+		fieldType := containerFs.typ.Field(i)
+		fieldIndex := append(slices.Clip(index), i)
+
+		// promote fields of inlined (and embedded) structs into parent
+		if v.isInlineField(fieldType) {
+			err := v.extractInlineFields(sd, parentFs, containerFs, fieldType, fieldIndex, names, inlined)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
 
 		tag := fieldType.Tag.Get("firevault")
 
@@ -270,10 +539,12 @@ func (v *validator) extractStructData(parentFs *fieldScope) (*structData, error)
 			field:        fieldType.Name,
 			structField:  fieldType.Name,
 			displayField: v.getDisplayName(fieldType.Name),
-			value:        fieldValue,
+			jsonField:    v.getJSONName(fieldType),
+			value:        v.fieldByIndex(parentFs.value, fieldIndex),
 			kind:         fieldType.Type.Kind(),
 			typ:          fieldType.Type,
 			dynamic:      parentFs.dynamic,
+			index:        fieldIndex,
 		}
 
 		// parse tag into separate rules
@@ -285,13 +556,24 @@ func (v *validator) extractStructData(parentFs *fieldScope) (*structData, error)
 		}
 
 		// get dot-separated field and struct path
-		fs.path = v.getFieldPath(parentFs.path, fs.field)
-		fs.structPath = v.getFieldPath(parentFs.structPath, fs.structField)
+		fs.path = v.getFieldPath(containerFs.path, fs.field)
+		fs.structPath = v.getFieldPath(containerFs.structPath, fs.structField)
+		fs.jsonPath = v.getFieldPath(containerFs.jsonPath, fs.jsonField)
+		fs.jsonPointer = v.getJSONPointer(containerFs.jsonPointer, fs.jsonField)
+
+		// check if field name is already used (e.g. by another inlined struct)
+		if other, ok := names[fs.field]; ok {
+			return fmt.Errorf(
+				"firevault: field name %s is used by both %s and %s", fs.path, other, fs.structPath,
+			)
+		}
+
+		names[fs.field] = fs.structPath
 
 		// check if field is of supported type
 		err := v.validateFieldType(fs.kind, fs.path)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		// check if and when field should be skipped based on provided rules
@@ -300,7 +582,11 @@ func (v *validator) extractStructData(parentFs *fieldScope) (*structData, error)
 		// check whether to dive into slice/map field
 		fs.dive = slices.Contains(rules, "dive")
 
-		// remove name, dive and omitempty from rules, so no validation is attempted
+		// check whether field can be written during an update
+		fs.immutable = slices.Contains(rules, "immutable")
+		fs.createOnly = slices.Contains(rules, "createonly")
+
+		// remove name, dive, omitempty, immutable and createonly from rules, so no validation is attempted
 		rules = v.cleanRules(rules)
 
 		// parse rules and generate rule data
@@ -317,12 +603,105 @@ func (v *validator) extractStructData(parentFs *fieldScope) (*structData, error)
 		}
 
 		// set cached struct field value
-		sd.fields[i] = fs
+		sd.fields = append(sd.fields, fs)
 	}
 
-	// store in cache
-	v.cache.set(parentFs.typ, sd)
-	return sd, nil
+	return nil
+}
+
+// collect fields data of an inlined struct, using its parent's paths
+func (v *validator) extractInlineFields(
+	sd *structData,
+	parentFs *fieldScope,
+	containerFs *fieldScope,
+	fieldType reflect.StructField,
+	index []int,
+	names map[string]string,
+	inlined map[reflect.Type]bool,
+) error {
+	structPath := v.getFieldPath(containerFs.structPath, fieldType.Name)
+
+	// only the inline rule (and a name, which is ignored) is allowed
+	if tag := fieldType.Tag.Get("firevault"); tag != "" {
+		for _, rule := range v.parseTag(tag)[1:] {
+			if rule != "inline" {
+				return errors.New("firevault: inline field cannot have other rules - " + structPath)
+			}
+		}
+	}
+
+	typ := derefType(fieldType.Type)
+	if typ.Kind() != reflect.Struct || typ == reflect.TypeOf(time.Time{}) {
+		return errors.New("firevault: inline field must be a struct - " + structPath)
+	}
+
+	// skip self-referencing embedded structs
+	if inlined[typ] {
+		return nil
+	}
+
+	inlined[typ] = true
+	defer func() { inlined[typ] = false }()
+
+	inlineFs := &fieldScope{
+		typ:         typ,
+		path:        containerFs.path,
+		structPath:  containerFs.structPath,
+		jsonPath:    containerFs.jsonPath,
+		jsonPointer: containerFs.jsonPointer,
+	}
+
+	// non-embedded fields keep their name in struct paths
+	if !fieldType.Anonymous {
+		inlineFs.structPath = structPath
+	}
+
+	// json only flattens embedded structs without a json name
+	if jsonName, _, _ := strings.Cut(fieldType.Tag.Get("json"), ","); !fieldType.Anonymous || jsonName != "" {
+		inlineFs.jsonPath = v.getFieldPath(containerFs.jsonPath, v.getJSONName(fieldType))
+		inlineFs.jsonPointer = v.getJSONPointer(containerFs.jsonPointer, v.getJSONName(fieldType))
+	}
+
+	return v.extractFields(sd, parentFs, inlineFs, index, names, inlined)
+}
+
+// check if a field's nested fields should be promoted into its parent
+// (only if tagged as such, as embedded structs without a tag are skipped, like other fields)
+func (v *validator) isInlineField(field reflect.StructField) bool {
+	tag := field.Tag.Get("firevault")
+	if tag == "" || tag == "-" {
+		return false
+	}
+
+	return slices.Contains(v.parseTag(tag)[1:], "inline")
+}
+
+// get a (possibly promoted) field's value, using the zero value if an embedded pointer is nil
+func (v *validator) fieldByIndex(strct reflect.Value, index []int) reflect.Value {
+	if len(index) == 1 {
+		return strct.Field(index[0])
+	}
+
+	val, err := strct.FieldByIndexErr(index)
+	if err != nil {
+		return reflect.Zero(strct.Type().FieldByIndex(index).Type)
+	}
+
+	return val
+}
+
+// get the underlying non-pointer type
+func derefType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	return typ
+}
+
+// check if type is a byte slice
+func isBytes(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8
 }
 
 // process individual field validations and transformations
@@ -336,6 +715,18 @@ func (v *validator) processStructField(
 		return "", nil, nil
 	}
 
+	// strip (or reject) fields which can't be written during an update
+	if opts.method == update && (fs.immutable || fs.createOnly) {
+		writable, err := v.isWritableOnUpdate(fs, opts)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if !writable {
+			return "", nil, nil
+		}
+	}
+
 	// skip empty field with omitempty tags
 	shouldOmit := fs.omitEmpty == all || fs.omitEmpty == opts.method
 	if shouldOmit && !slices.Contains(opts.emptyFieldsAllowed, fs.path) && !hasValue(fs.kind, fs.value) {
@@ -350,6 +741,10 @@ func (v *validator) processStructField(
 	// handle pointers
 	if fs.pointer {
 		fs.value = fs.value.Elem()
+
+		if fs.prev.IsValid() {
+			fs.prev = fs.prev.Elem()
+		}
 	}
 
 	// check if validation should be skipped for this field
@@ -372,7 +767,8 @@ func (v *validator) processStructField(
 		// check if original struct value should be changed (can be thread-unsafe, hence option)
 		if opts.modifyOriginal {
 			// set original struct's field value if changed
-			if fieldValue != fs.value {
+			// (unless unsettable, e.g. promoted through a nil embedded pointer)
+			if fieldValue != fs.value && fieldValue.CanSet() {
 				// use fieldValue as that stores the original field value
 				fieldValue.Set(fs.value)
 			}
@@ -388,6 +784,34 @@ func (v *validator) processStructField(
 	return fs.field, finalValue, nil
 }
 
+// check if immutable or createonly field can be written during an update
+// (without a previous document to compare against, any set immutable field is treated as a change)
+func (v *validator) isWritableOnUpdate(fs *fieldScope, opts validationOpts) (bool, error) {
+	// unset fields are always skipped (and never deleted)
+	if !hasValue(fs.kind, fs.value) {
+		return false, nil
+	}
+
+	// immutable fields can be set, if not previously stored or left unchanged
+	if fs.immutable && !fs.createOnly && fs.prev.IsValid() {
+		if fs.prev.IsZero() || reflect.DeepEqual(fs.value.Interface(), fs.prev.Interface()) {
+			return true, nil
+		}
+	}
+
+	if !opts.rejectImmutable {
+		return false, nil
+	}
+
+	fs.rule = "immutable"
+	if fs.createOnly {
+		fs.rule = "createonly"
+	}
+	fs.param = ""
+
+	return false, v.generateFieldErr(fs)
+}
+
 // parse rule tags
 func (v *validator) parseTag(tag string) []string {
 	rules := strings.Split(tag, ",")
@@ -410,6 +834,24 @@ func (v *validator) getFieldPath(path string, fieldName string) string {
 	return path + "." + fieldName
 }
 
+// get RFC 6901 JSON Pointer, escaping reserved characters
+func (v *validator) getJSONPointer(pointer string, fieldName string) string {
+	fieldName = strings.ReplaceAll(fieldName, "~", "~0")
+	fieldName = strings.ReplaceAll(fieldName, "/", "~1")
+
+	return pointer + "/" + fieldName
+}
+
+// get field name from json tag, falling back to struct name
+func (v *validator) getJSONName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+
+	return name
+}
+
 // get field struct name in a human-readable form
 func (v *validator) getDisplayName(fieldName string) string {
 	// handle snake case - replace underscores with spaces
@@ -463,14 +905,14 @@ func (v *validator) shouldSkipField(rules []string) methodType {
 	return none
 }
 
-// remove name, dive and omitempty from rules
+// remove name, dive, omitempty, immutable and createonly from rules
 func (v *validator) cleanRules(rules []string) []string {
 	cleanedRules := make([]string, 0, len(rules))
 
 	for index, rule := range rules {
 		if index != 0 && rule != "omitempty" && rule != string("omitempty_"+create) &&
 			rule != string("omitempty_"+update) && rule != string("omitempty_"+validate) &&
-			rule != "dive" {
+			rule != "dive" && rule != "immutable" && rule != "createonly" {
 			cleanedRules = append(cleanedRules, rule)
 		}
 	}
@@ -488,6 +930,7 @@ func (v *validator) extractRuleData(rules []string) []*ruleData {
 		var transFn tranFuncInternal
 		var param string
 		var runOnNil bool
+		var marshalled bool
 		var methodOnly methodType
 
 		if strings.HasSuffix(rule, string("_"+create)) {
@@ -518,6 +961,7 @@ func (v *validator) extractRuleData(rules []string) []*ruleData {
 
 			valFn = valWrapper.fn
 			runOnNil = valWrapper.runOnNil
+			marshalled = valWrapper.marshalled
 		}
 
 		rulesData = append(rulesData, &ruleData{
@@ -527,6 +971,7 @@ func (v *validator) extractRuleData(rules []string) []*ruleData {
 			isTransform: isTransform,
 			param:       param,
 			runOnNil:    runOnNil,
+			marshalled:  marshalled,
 			methodOnly:  methodOnly,
 		})
 	}
@@ -618,13 +1063,23 @@ func (v *validator) applyValidation(
 		return nil
 	}
 
-	valid, err := rule.valFn(ctx, tx, fs)
+	valFs := fs
+	if rule.marshalled {
+		var err error
+
+		valFs, err = v.marshalledScope(fs)
+		if err != nil {
+			return err
+		}
+	}
+
+	valid, err := rule.valFn(ctx, tx, valFs)
 	if err != nil {
 		return err
 	}
 
 	if !valid {
-		return v.generateFieldErr(fs)
+		return v.generateFieldErr(valFs)
 	}
 
 	return nil
@@ -641,6 +1096,16 @@ func (v *validator) processFinalValue(
 		return nil, nil
 	}
 
+	// return Firestore's special types directly (e.g. references and geopoints)
+	if v.isSpecialType(fs.typ) {
+		return v.specialValue(fs.value), nil
+	}
+
+	// use Firestore value of custom types
+	if value, ok, err := v.marshalValue(fs.value); ok {
+		return value, err
+	}
+
 	switch fs.kind {
 	case reflect.Struct:
 		// handle time.Time
@@ -650,19 +1115,21 @@ func (v *validator) processFinalValue(
 
 		return v.validateStructFields(ctx, fs, opts)
 	case reflect.Map:
-		// return map directly, without validating nested fields
-		if !fs.dive {
+		// return map directly, without validating nested fields (unless holding custom types)
+		if !fs.dive && !v.isValuer(fs.typ.Elem()) {
 			return fs.value.Interface(), nil
 		}
 
 		return v.processMapValue(ctx, fs, opts)
 	case reflect.Array, reflect.Slice:
-		// return slice/array directly, without validating nested fields
-		if !fs.dive {
+		// return slice/array directly, without validating nested fields (unless holding custom types)
+		if !fs.dive && !v.isValuer(fs.typ.Elem()) {
 			return fs.value.Interface(), nil
 		}
 
 		return v.processSliceValue(ctx, fs, opts)
+	case reflect.Interface:
+		return v.processInterfaceValue(ctx, fs, opts)
 	default:
 		return fs.value.Interface(), nil
 	}
@@ -682,9 +1149,24 @@ func (v *validator) processMapValue(
 		val := iter.Value()
 		kind := val.Kind()
 
+		var prev reflect.Value
+		if parentFs.prev.IsValid() && parentFs.prev.Kind() == reflect.Map {
+			prev = parentFs.prev.MapIndex(key)
+		}
+
 		if kind == reflect.Pointer {
 			val = val.Elem()
 			kind = val.Kind()
+
+			if prev.IsValid() {
+				prev = prev.Elem()
+			}
+		}
+
+		// nil pointer elements are stored as nil
+		if !val.IsValid() {
+			newMap[key.String()] = nil
+			continue
 		}
 
 		fs := &fieldScope{
@@ -694,9 +1176,15 @@ func (v *validator) processMapValue(
 			structField: key.String(),
 			path:        fmt.Sprintf("%s.%v", parentFs.path, key.Interface()),
 			structPath:  fmt.Sprintf("%s.%v", parentFs.structPath, key.Interface()),
+			jsonField:   key.String(),
+			jsonPath:    fmt.Sprintf("%s.%v", parentFs.jsonPath, key.Interface()),
+			jsonPointer: v.getJSONPointer(parentFs.jsonPointer, fmt.Sprint(key.Interface())),
 			value:       val,
 			kind:        kind,
 			typ:         val.Type(),
+			parent:      parentFs,
+			opts:        parentFs.opts,
+			prev:        prev,
 			dynamic:     true,
 		}
 
@@ -723,9 +1211,23 @@ func (v *validator) processSliceValue(
 		val := parentFs.value.Index(i)
 		kind := val.Kind()
 
+		var prev reflect.Value
+		if parentFs.prev.IsValid() && i < parentFs.prev.Len() {
+			prev = parentFs.prev.Index(i)
+		}
+
 		if kind == reflect.Pointer {
 			val = val.Elem()
 			kind = val.Kind()
+
+			if prev.IsValid() {
+				prev = prev.Elem()
+			}
+		}
+
+		// nil pointer elements are stored as nil
+		if !val.IsValid() {
+			continue
 		}
 
 		fs := &fieldScope{
@@ -735,9 +1237,15 @@ func (v *validator) processSliceValue(
 			structField: fmt.Sprintf("[%d]", i),
 			path:        fmt.Sprintf("%s[%d]", parentFs.path, i),
 			structPath:  fmt.Sprintf("%s[%d]", parentFs.structPath, i),
+			jsonField:   fmt.Sprintf("[%d]", i),
+			jsonPath:    fmt.Sprintf("%s[%d]", parentFs.jsonPath, i),
+			jsonPointer: fmt.Sprintf("%s/%d", parentFs.jsonPointer, i),
 			value:       val,
 			kind:        kind,
 			typ:         val.Type(),
+			parent:      parentFs,
+			opts:        parentFs.opts,
+			prev:        prev,
 			dynamic:     true,
 		}
 
@@ -761,16 +1269,28 @@ func (v *validator) generateFieldErr(fs *fieldScope) error {
 		displayField: fs.displayField,
 		path:         fs.path,
 		structPath:   fs.structPath,
+		jsonField:    fs.jsonField,
+		jsonPath:     fs.jsonPath,
+		jsonPointer:  fs.jsonPointer,
 		value:        fs.value,
 		kind:         fs.kind,
 		typ:          fs.typ,
 		rule:         fs.rule,
 		param:        fs.param,
+		prev:         fs.prev,
 	}
 
 	for _, formatter := range v.errFormatters {
 		err := formatter(fe)
 		if err != nil {
+			fe.message = err.Error()
+			return err
+		}
+	}
+
+	if formatter, ok := v.ruleErrFormatters[fe.rule]; ok {
+		if err := formatter(fe); err != nil {
+			fe.message = err.Error()
 			return err
 		}
 	}
@@ -0,0 +1,235 @@
+package firevault
+
+import (
+	"context"
+	"errors"
+)
+
+// Validator validates and transforms structs,
+// based on their Firevault tags, without
+// requiring a Firestore connection.
+//
+// It holds all registered rules, error
+// formatters, transition graphs and schema
+// rules. Connection embeds a Validator, so
+// the exact same rule set can be reused
+// in-process (e.g. to validate HTTP request
+// bodies, or in CLI tools and tests).
+//
+// It is designed to be thread-safe and used
+// as a singleton instance.
+//
+// A cache is used under the hood to store
+// struct validation metadata, parsing
+// validation tags once per struct type.
+type Validator struct {
+	validator *validator
+}
+
+// Create a new Validator instance.
+//
+// To share rules with a Connection, use the
+// Validator embedded in it instead.
+func NewValidator() *Validator {
+	return &Validator{newValidator()}
+}
+
+// Validate and transform provided data, which
+// must be a pointer to a struct.
+//
+// Method-specific rules are applied as during
+// calls to CollectionRef's Validate method,
+// unless changed via Options (e.g. using
+// AsCreate or AsUpdate).
+//
+// Options which require a Firestore connection
+// (e.g. LoadPrevious) have no effect.
+func (v *Validator) Validate(ctx context.Context, data interface{}, opts ...Options) error {
+	if v == nil {
+		return errors.New("firevault: nil Validator")
+	}
+
+	valOpts := parseValidationOpts("", validate, opts...)
+
+	_, err := v.validator.validate(ctx, data, valOpts)
+	return err
+}
+
+// Register a new validation rule.
+//
+// If a validation rule with the same name
+// already exists, the previous one will be replaced.
+//
+// If the validation name includes a method-specific
+// suffix ("_create", "_update", or "_validate"),
+// the rule will be applied exclusively during
+// calls to the corresponding method type and
+// ignored for others.
+//
+// Registering such functions is not thread-safe;
+// it is intended that all rules be registered,
+// prior to any validation.
+func (v *Validator) RegisterValidation(
+	name string,
+	validation Validation,
+	runOnNil ...bool,
+) error {
+	if v == nil {
+		return errors.New("firevault: nil Validator")
+	}
+
+	var nilCallable bool
+	if len(runOnNil) > 0 {
+		nilCallable = runOnNil[0]
+	}
+
+	return v.validator.registerValidation(
+		name,
+		validation.toValFuncInternal(),
+		false,
+		nilCallable,
+	)
+}
+
+// Register a new transformation rule.
+//
+// If a transformation rule with the same name
+// already exists, the previous one will be replaced.
+//
+// If the transformation name includes a
+// method-specific suffix
+// ("_create", "_update", or "_validate"),
+// the rule will be applied exclusively during
+// calls to the corresponding method type and
+// ignored for others.
+//
+// Registering such functions is not thread-safe;
+// it is intended that all rules be registered,
+// prior to any validation.
+func (v *Validator) RegisterTransformation(
+	name string,
+	transformation Transformation,
+	runOnNil ...bool,
+) error {
+	if v == nil {
+		return errors.New("firevault: nil Validator")
+	}
+
+	var nilCallable bool
+	if len(runOnNil) > 0 {
+		nilCallable = runOnNil[0]
+	}
+
+	return v.validator.registerTransformation(
+		name,
+		transformation.toTranFuncInternal(),
+		false,
+		nilCallable,
+	)
+}
+
+// Register a new error formatter.
+//
+// Error formatters are used to generate a custom,
+// user-friendly error message, whenever a
+// FieldError is created (during a failed validation).
+//
+// If none are registered, or if a formatter returns
+// a nil error, an instance of a FieldError will be
+// returned instead.
+//
+// Registering error formatters is not thread-safe;
+// it is intended that all such functions
+// be registered, prior to any validation.
+func (v *Validator) RegisterErrorFormatter(errorFormatter ErrorFormatterFunc) error {
+	if v == nil {
+		return errors.New("firevault: nil Validator")
+	}
+
+	return v.validator.registerErrorFormatter(errorFormatter)
+}
+
+// Register a new transition graph.
+//
+// Transition graphs define which state changes
+// are allowed for a field, mapping each state
+// to the states it can move to. They can be
+// referenced using the "transitions" rule
+// (e.g. "status,transitions=order_status").
+//
+// The empty string key can be used to restrict
+// the initial states (i.e. when no value is
+// stored yet). If omitted, any initial state is
+// allowed.
+//
+// During an Update, the stored documents are
+// read, in order to validate the transition.
+// Therefore, Update must be called inside a
+// transaction (via Options) to be race-free.
+//
+// If a graph with the same name already exists,
+// the previous one will be replaced.
+//
+// Registering transition graphs is not
+// thread-safe; it is intended that all graphs
+// be registered, prior to any validation.
+func (v *Validator) RegisterTransitions(name string, transitions map[string][]string) error {
+	if v == nil {
+		return errors.New("firevault: nil Validator")
+	}
+
+	return v.validator.registerTransitions(name, transitions)
+}
+
+// Register a new schema rule.
+//
+// Schema rules contribute a JSON Schema fragment
+// for a custom validation rule with the same
+// name, whenever a schema is generated (using
+// JSONSchema or OpenAPISchemas).
+//
+// If a schema rule with the same name already
+// exists, the previous one will be replaced.
+//
+// Registering schema rules is not thread-safe;
+// it is intended that all rules be registered,
+// prior to any schema generation.
+func (v *Validator) RegisterSchemaRule(name string, schemaRule SchemaRuleFunc) error {
+	if v == nil {
+		return errors.New("firevault: nil Validator")
+	}
+
+	return v.validator.registerSchemaRule(name, schemaRule)
+}
+
+// Register a concrete type under a type key.
+//
+// Registered types can be stored in fields
+// declared as interface types, as well as in
+// collections whose documents come in several
+// shapes (i.e. CollectionRef with an interface
+// type). Such values are validated against the
+// concrete type's rules, while the type key is
+// written alongside their data, under the
+// DiscriminatorField, and used to decode them
+// back into the right concrete type.
+//
+// The value must be a struct, or a pointer to
+// one. Decoded values are stored as a pointer
+// only if the struct itself doesn't implement
+// the interface.
+//
+// If the same key (or type) is already
+// registered, the previous registration will be
+// replaced.
+//
+// Registering types is not thread-safe; it is
+// intended that all types be registered, prior
+// to any validation.
+func (v *Validator) RegisterType(key string, value interface{}) error {
+	if v == nil {
+		return errors.New("firevault: nil Validator")
+	}
+
+	return v.validator.registerType(key, value)
+}
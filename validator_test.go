@@ -2,11 +2,19 @@ package firevault
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
 )
 
 func TestValidate(t *testing.T) {
@@ -495,3 +503,1238 @@ func TestRegisterErrorFormatter(t *testing.T) {
 		})
 	}
 }
+
+func TestFieldErrorJSON(t *testing.T) {
+	type Name struct {
+		First string `firevault:"first_name,required" json:"firstName"`
+	}
+
+	type TestStruct struct {
+		Names Name           `firevault:"names" json:"names"`
+		Tags  []Name         `firevault:"tags,dive" json:"tags"`
+		Meta  map[string]int `firevault:"meta" json:"meta,omitempty"`
+		Age   int            `firevault:"age,min=18"`
+	}
+
+	tests := []struct {
+		name        string
+		data        interface{}
+		wantPath    string
+		wantPointer string
+		wantField   string
+	}{
+		{
+			name:        "Nested struct field",
+			data:        &TestStruct{Tags: []Name{{First: "John"}}, Age: 20},
+			wantPath:    "names.firstName",
+			wantPointer: "/names/firstName",
+			wantField:   "firstName",
+		},
+		{
+			name:        "Slice element field",
+			data:        &TestStruct{Names: Name{First: "John"}, Tags: []Name{{First: "Jane"}, {}}, Age: 20},
+			wantPath:    "tags[1].firstName",
+			wantPointer: "/tags/1/firstName",
+			wantField:   "firstName",
+		},
+		{
+			name:        "Field without json tag",
+			data:        &TestStruct{Names: Name{First: "John"}, Age: 17},
+			wantPath:    "Age",
+			wantPointer: "/Age",
+			wantField:   "Age",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newValidator()
+			_, valErr := v.validate(context.Background(), tt.data, validationOpts{method: create})
+
+			var fe JSONFieldError
+			if !errors.As(valErr, &fe) {
+				t.Fatalf("Expected JSONFieldError, got %v", valErr)
+			}
+
+			if fe.JSONPath() != tt.wantPath {
+				t.Errorf("FieldError.JSONPath() = %v, want %v", fe.JSONPath(), tt.wantPath)
+			}
+			if fe.JSONPointer() != tt.wantPointer {
+				t.Errorf("FieldError.JSONPointer() = %v, want %v", fe.JSONPointer(), tt.wantPointer)
+			}
+
+			b, err := json.Marshal(fe)
+			if err != nil {
+				t.Fatalf("Failed to marshal FieldError: %v", err)
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatalf("Failed to unmarshal FieldError: %v", err)
+			}
+			if got["field"] != tt.wantField || got["pointer"] != tt.wantPointer {
+				t.Errorf("Unexpected FieldError JSON: %s", b)
+			}
+
+			// Firevault names (e.g. "first_name") are never exposed
+			wantMessage := "field validation for '" + tt.wantField + "' failed on the '" + fe.Rule() + "' rule"
+			if got["message"] != wantMessage {
+				t.Errorf("FieldError JSON message = %v, want %v", got["message"], wantMessage)
+			}
+
+			pd := NewProblemDetails(fmt.Errorf("wrapped: %w", valErr), 0)
+			if pd.Status != http.StatusUnprocessableEntity || len(pd.Errors) != 1 {
+				t.Errorf("Unexpected ProblemDetails: %+v", pd)
+			}
+		})
+	}
+
+	// messages of error formatters are used, with the wrapped FieldError using json names
+	v := newValidator()
+	err := v.registerErrorFormatter(func(fe FieldError) error {
+		if fe.Rule() == "min" {
+			return fmt.Errorf("%w (must be an adult)", fe)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to register error formatter: %v", err)
+	}
+
+	_, valErr := v.validate(context.Background(), &TestStruct{Names: Name{First: "John"}, Age: 17}, validationOpts{method: create})
+
+	b, err := json.Marshal(NewProblemDetails(valErr, 0))
+	if err != nil {
+		t.Fatalf("Failed to marshal ProblemDetails: %v", err)
+	}
+
+	if !strings.Contains(string(b), `"message":"field validation for 'Age' failed on the 'min' rule (must be an adult)"`) {
+		t.Errorf("Expected formatted message in JSON, got %s", b)
+	}
+}
+
+func TestFieldScopeContext(t *testing.T) {
+	type Item struct {
+		Name string `firevault:"name,check"`
+	}
+
+	type TestStruct struct {
+		Title string `firevault:"title"`
+		Items []Item `firevault:"items,dive"`
+	}
+
+	var got FieldScope
+
+	v := newValidator()
+	err := v.registerValidation(
+		"check",
+		func(ctx context.Context, tx *Transaction, fs FieldScope) (bool, error) {
+			got = fs
+			return true, nil
+		},
+		false,
+		false,
+	)
+	if err != nil {
+		t.Fatalf("Failed to register validation: %v", err)
+	}
+
+	data := &TestStruct{Title: "root", Items: []Item{{Name: "item"}}}
+	_, err = v.validate(context.Background(), data, validationOpts{
+		method: update,
+		docIDs: []string{"doc1"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got.Method() != "update" {
+		t.Errorf("FieldScope.Method() = %v, want update", got.Method())
+	}
+	if got.DocumentID() != "doc1" {
+		t.Errorf("FieldScope.DocumentID() = %v, want doc1", got.DocumentID())
+	}
+	if got.Root().FieldByName("Title").String() != "root" {
+		t.Errorf("FieldScope.Root() returned unexpected struct")
+	}
+
+	// walk up: item struct -> slice element -> items field (top-level fields have no parent)
+	paths := []string{}
+	for p := got.Parent(); p != nil; p = p.Parent() {
+		paths = append(paths, p.Path())
+	}
+
+	want := []string{"items[0]", "items"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("FieldScope.Parent() chain = %v, want %v", paths, want)
+	}
+}
+
+func TestFieldScopeConcurrent(t *testing.T) {
+	type TestStruct struct {
+		Name string `firevault:"name,check"`
+	}
+
+	v := newValidator()
+	err := v.registerValidation(
+		"check",
+		func(ctx context.Context, tx *Transaction, fs FieldScope) (bool, error) {
+			// each validation must only see its own state
+			return fs.DocumentID() == fs.Value().String(), nil
+		},
+		false,
+		false,
+	)
+	if err != nil {
+		t.Fatalf("Failed to register validation: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func(id string) {
+			defer wg.Done()
+
+			_, err := v.validate(context.Background(), &TestStruct{Name: id}, validationOpts{
+				method: update,
+				docIDs: []string{id},
+			})
+			if err != nil {
+				errs <- err
+			}
+		}(fmt.Sprintf("doc%d", i))
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestPreviousValue(t *testing.T) {
+	type Item struct {
+		Status string `firevault:"status,forward"`
+	}
+
+	type TestStruct struct {
+		Status string          `firevault:"status,forward"`
+		Owner  *string         `firevault:"owner,unchanged"`
+		Items  map[string]Item `firevault:"items,dive"`
+	}
+
+	order := map[string]int{"open": 0, "pending": 1, "closed": 2}
+
+	v := newValidator()
+	_ = v.registerValidation(
+		"forward",
+		func(ctx context.Context, tx *Transaction, fs FieldScope) (bool, error) {
+			prev := fs.Previous()
+			if !prev.IsValid() {
+				return true, nil
+			}
+
+			return order[fs.Value().String()] >= order[prev.String()], nil
+		},
+		false,
+		false,
+	)
+	_ = v.registerValidation(
+		"unchanged",
+		func(ctx context.Context, tx *Transaction, fs FieldScope) (bool, error) {
+			prev := fs.Previous()
+			return !prev.IsValid() || prev.String() == fs.Value().String(), nil
+		},
+		false,
+		false,
+	)
+
+	owner := "john"
+	other := "jane"
+	prev := &TestStruct{
+		Status: "pending",
+		Owner:  &owner,
+		Items:  map[string]Item{"a": {Status: "closed"}},
+	}
+
+	tests := []struct {
+		name     string
+		data     *TestStruct
+		previous reflect.Value
+		wantErr  bool
+		wantPath string
+	}{
+		{
+			name:     "Valid forward transition",
+			data:     &TestStruct{Status: "closed", Owner: &owner},
+			previous: reflect.ValueOf(prev).Elem(),
+		},
+		{
+			name:     "Invalid backward transition",
+			data:     &TestStruct{Status: "open", Owner: &owner},
+			previous: reflect.ValueOf(prev).Elem(),
+			wantErr:  true,
+			wantPath: "status",
+		},
+		{
+			name:     "Invalid pointer field change",
+			data:     &TestStruct{Status: "closed", Owner: &other},
+			previous: reflect.ValueOf(prev).Elem(),
+			wantErr:  true,
+			wantPath: "owner",
+		},
+		{
+			name:     "Invalid nested map transition",
+			data:     &TestStruct{Status: "closed", Items: map[string]Item{"a": {Status: "open"}}},
+			previous: reflect.ValueOf(prev).Elem(),
+			wantErr:  true,
+			wantPath: "items.a.status",
+		},
+		{
+			name: "No previous document",
+			data: &TestStruct{Status: "open", Owner: &other},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.validate(context.Background(), tt.data, validationOpts{
+				method:   update,
+				previous: tt.previous,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validator.validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var fe FieldError
+			if tt.wantErr && (!errors.As(err, &fe) || fe.Path() != tt.wantPath) {
+				t.Errorf("Expected FieldError for %s, got %v", tt.wantPath, err)
+			}
+		})
+	}
+}
+
+func TestImmutableFields(t *testing.T) {
+	type TestStruct struct {
+		Name    string `firevault:"name"`
+		Owner   string `firevault:"owner,immutable"`
+		Creator string `firevault:"creator,createonly"`
+	}
+
+	prev := reflect.ValueOf(&TestStruct{Creator: "jane"}).Elem()
+	prevOwned := reflect.ValueOf(&TestStruct{Owner: "john", Creator: "jane"}).Elem()
+
+	tests := []struct {
+		name     string
+		data     *TestStruct
+		opts     validationOpts
+		want     map[string]interface{}
+		wantRule string
+	}{
+		{
+			name: "Written during create",
+			data: &TestStruct{Name: "doc", Owner: "john", Creator: "jane"},
+			opts: validationOpts{method: create},
+			want: map[string]interface{}{"name": "doc", "owner": "john", "creator": "jane"},
+		},
+		{
+			name: "Stripped during update",
+			data: &TestStruct{Name: "doc", Owner: "john", Creator: "jane"},
+			opts: validationOpts{method: update},
+			want: map[string]interface{}{"name": "doc"},
+		},
+		{
+			name: "Not deleted during update with replace all",
+			data: &TestStruct{Name: "doc"},
+			opts: validationOpts{method: update, deleteEmpty: true},
+			want: map[string]interface{}{"name": "doc"},
+		},
+		{
+			name: "Immutable set when not previously stored",
+			data: &TestStruct{Name: "doc", Owner: "john", Creator: "jim"},
+			opts: validationOpts{method: update, previous: prev},
+			want: map[string]interface{}{"name": "doc", "owner": "john"},
+		},
+		{
+			name:     "Rejected immutable during update",
+			data:     &TestStruct{Name: "doc", Owner: "john"},
+			opts:     validationOpts{method: update, rejectImmutable: true},
+			wantRule: "immutable",
+		},
+		{
+			name: "Unchanged immutable not rejected",
+			data: &TestStruct{Name: "doc", Owner: "john"},
+			opts: validationOpts{method: update, previous: prevOwned, rejectImmutable: true},
+			want: map[string]interface{}{"name": "doc", "owner": "john"},
+		},
+		{
+			name:     "Changed immutable rejected",
+			data:     &TestStruct{Name: "doc", Owner: "jim"},
+			opts:     validationOpts{method: update, previous: prevOwned, rejectImmutable: true},
+			wantRule: "immutable",
+		},
+		{
+			name:     "Rejected createonly during update",
+			data:     &TestStruct{Name: "doc", Creator: "jane"},
+			opts:     validationOpts{method: update, rejectImmutable: true},
+			wantRule: "createonly",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newValidator()
+			result, err := v.validate(context.Background(), tt.data, tt.opts)
+
+			if tt.wantRule != "" {
+				var fe FieldError
+				if !errors.As(err, &fe) || fe.Rule() != tt.wantRule {
+					t.Errorf("Expected FieldError on %s rule, got %v", tt.wantRule, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.want) {
+				t.Errorf("validator.validate() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	type Order struct {
+		Status string `firevault:"status,required,transitions=order_status"`
+	}
+
+	type Wrapper struct {
+		Orders []Order `firevault:"orders,dive"`
+	}
+
+	v := newValidator()
+	err := v.registerTransitions("order_status", map[string][]string{
+		"":        {"pending"},
+		"pending": {"paid", "cancelled"},
+		"paid":    {"shipped"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to register transitions: %v", err)
+	}
+
+	if !v.usesRule(reflect.TypeOf(&Wrapper{}), "transitions") {
+		t.Errorf("validator.usesRule() = false, want true")
+	}
+
+	tests := []struct {
+		name     string
+		status   string
+		previous string
+		method   methodType
+		wantErr  bool
+	}{
+		{"Allowed initial state", "pending", "", create, false},
+		{"Disallowed initial state", "paid", "", create, true},
+		{"Allowed transition", "paid", "pending", update, false},
+		{"Disallowed transition", "shipped", "pending", update, true},
+		{"Unchanged state", "paid", "paid", update, false},
+		{"Unknown previous state", "paid", "archived", update, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := validationOpts{method: tt.method}
+			if tt.previous != "" {
+				opts.previous = reflect.ValueOf(&Order{Status: tt.previous}).Elem()
+			}
+
+			_, err := v.validate(context.Background(), &Order{Status: tt.status}, opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validator.validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var fe FieldError
+			if tt.wantErr && (!errors.As(err, &fe) || !strings.Contains(err.Error(), "to '"+tt.status+"'")) {
+				t.Errorf("Expected FieldError describing transition, got %v", err)
+			}
+
+			var tfe TransitionFieldError
+			if tt.wantErr && (!errors.As(err, &tfe) || tfe.Previous().IsValid() != (tt.previous != "")) {
+				t.Errorf("Expected TransitionFieldError with previous value %q, got %v", tt.previous, err)
+			}
+
+			if tt.wantErr {
+				b, _ := json.Marshal(fe)
+				if !strings.Contains(string(b), "(transition from '"+tt.previous+"' to '"+tt.status+"' is not allowed)") {
+					t.Errorf("Expected JSON message describing transition, got %s", b)
+				}
+			}
+		})
+	}
+
+	// registered formatters take precedence over the transition description
+	err = v.registerErrorFormatter(func(fe FieldError) error {
+		return errors.New("invalid status")
+	})
+	if err != nil {
+		t.Fatalf("Failed to register error formatter: %v", err)
+	}
+
+	_, err = v.validate(context.Background(), &Order{Status: "paid"}, validationOpts{method: create})
+	if err == nil || err.Error() != "invalid status" {
+		t.Errorf("validator.validate() error = %v, want invalid status", err)
+	}
+}
+
+type testStatus int
+
+func (s testStatus) FirestoreValue() (interface{}, error) {
+	return [...]string{"", "pending", "paid", "shipped"}[s], nil
+}
+
+func TestTransitionsCustomType(t *testing.T) {
+	type Order struct {
+		Status testStatus `firevault:"status,transitions=order_status"`
+	}
+
+	type PtrOrder struct {
+		Status *testStatus `firevault:"status,transitions=order_status"`
+	}
+
+	v := newValidator()
+	err := v.registerTransitions("order_status", map[string][]string{
+		"pending": {"paid"},
+		"paid":    {"shipped"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to register transitions: %v", err)
+	}
+
+	pending, paid, shipped := testStatus(1), testStatus(2), testStatus(3)
+
+	tests := []struct {
+		name     string
+		data     interface{}
+		previous interface{}
+		wantErr  bool
+	}{
+		{"Allowed transition", &Order{paid}, &Order{pending}, false},
+		{"Unchanged state", &Order{paid}, &Order{paid}, false},
+		{"Disallowed transition", &Order{shipped}, &Order{pending}, true},
+		{"Allowed pointer transition", &PtrOrder{&paid}, &PtrOrder{&pending}, false},
+		{"Disallowed pointer transition", &PtrOrder{&pending}, &PtrOrder{&shipped}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := validationOpts{method: update, previous: reflect.ValueOf(tt.previous).Elem()}
+
+			var err error
+			switch data := tt.data.(type) {
+			case *Order:
+				_, err = v.validate(context.Background(), data, opts)
+			case *PtrOrder:
+				_, err = v.validate(context.Background(), data, opts)
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validator.validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// states are described by their marshalled values
+			if tt.wantErr && (!strings.Contains(err.Error(), "'pending'") || !strings.Contains(err.Error(), "'shipped'")) {
+				t.Errorf("Expected error describing marshalled states, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStandaloneValidator(t *testing.T) {
+	type User struct {
+		Name  string `firevault:"name,required,transform:uppercase"`
+		Email string `firevault:"email,required_create,is_company_email"`
+	}
+
+	v := NewValidator()
+
+	err := v.RegisterValidation("is_company_email", ValidationFunc(func(fs FieldScope) (bool, error) {
+		return fs.Value().String() == "" || strings.HasSuffix(fs.Value().String(), "@company.com"), nil
+	}))
+	if err != nil {
+		t.Fatalf("Failed to register validation: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		data     *User
+		opts     []Options
+		wantErr  bool
+		wantName string
+	}{
+		{"Valid", &User{Name: "jo", Email: "jo@company.com"}, nil, false, "jo"},
+		{"Custom validation failure", &User{Name: "jo", Email: "jo@other.com"}, nil, true, "jo"},
+		{"Method-specific rule", &User{Name: "jo"}, []Options{NewOptions().AsCreate()}, true, "jo"},
+		{"Modified original", &User{Name: "jo"}, []Options{NewOptions().ModifyOriginal()}, false, "JO"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(context.Background(), tt.data, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validator.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.data.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", tt.data.Name, tt.wantName)
+			}
+		})
+	}
+
+	// connection shares the rules of its embedded validator
+	connection := &Connection{Validator: v}
+	if _, ok := connection.validator.validations["is_company_email"]; !ok {
+		t.Errorf("Expected connection to share validator rules")
+	}
+
+	var nilValidator *Validator
+	if err := nilValidator.Validate(context.Background(), &User{}); err == nil {
+		t.Errorf("Expected error for nil Validator")
+	}
+}
+
+func TestNilConnection(t *testing.T) {
+	var connection *Connection
+
+	errs := []error{
+		connection.Validate(context.Background(), &struct{}{}),
+		connection.RegisterValidation("rule", ValidationFunc(func(fs FieldScope) (bool, error) {
+			return true, nil
+		})),
+		connection.RegisterTransformation("rule", TransformationFunc(func(fs FieldScope) (interface{}, error) {
+			return fs.Value().Interface(), nil
+		})),
+		connection.RegisterErrorFormatter(func(fe FieldError) error { return nil }),
+		connection.RegisterTransitions("graph", map[string][]string{"": {"open"}}),
+		connection.RegisterSchemaRule("rule", func(param string, schema Schema) {}),
+		connection.RegisterType("type", struct{}{}),
+	}
+
+	for i, err := range errs {
+		if err == nil || err.Error() != "firevault: nil Connection" {
+			t.Errorf("Call %d error = %v, want nil Connection", i, err)
+		}
+	}
+}
+
+func TestInlineFields(t *testing.T) {
+	type Audit struct {
+		CreatedBy string `firevault:"created_by,required" json:"createdBy"`
+		UpdatedBy string `firevault:"updated_by,omitempty"`
+	}
+
+	type Tenant struct {
+		TenantID string `firevault:"tenant_id,required"`
+	}
+
+	type Meta struct {
+		Source string `firevault:"source"`
+	}
+
+	type Document struct {
+		Audit  `firevault:",inline"`
+		*Meta  `firevault:",inline"`
+		Tenant Tenant `firevault:",inline"`
+		Name   string `firevault:"name,required"`
+	}
+
+	type Untagged struct {
+		Audit
+		Name string `firevault:"name"`
+	}
+
+	type Nested struct {
+		Audit `firevault:"audit"`
+		Name  string `firevault:"name"`
+	}
+
+	type Conflict struct {
+		Audit     `firevault:",inline"`
+		CreatedBy string `firevault:"created_by"`
+	}
+
+	type InvalidRules struct {
+		Tenant Tenant `firevault:",inline,required"`
+	}
+
+	type InvalidType struct {
+		Name string `firevault:",inline"`
+	}
+
+	v := newValidator()
+
+	got, err := v.validate(context.Background(), &Document{
+		Audit:  Audit{CreatedBy: "admin"},
+		Tenant: Tenant{TenantID: "t1"},
+		Name:   "doc",
+	}, validationOpts{method: create})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"created_by": "admin",
+		"tenant_id":  "t1",
+		"source":     "",
+		"name":       "doc",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Inlined data = %v, want %v", got, want)
+	}
+
+	// paths of promoted fields don't include the inlined struct
+	_, err = v.validate(context.Background(), &Document{Name: "doc"}, validationOpts{method: create})
+
+	var fe JSONFieldError
+	if !errors.As(err, &fe) || fe.Path() != "created_by" || fe.StructPath() != "CreatedBy" ||
+		fe.JSONPath() != "createdBy" {
+		t.Errorf("Expected FieldError with promoted paths, got %v", err)
+	}
+
+	_, err = v.validate(context.Background(), &Document{Audit: Audit{CreatedBy: "admin"}, Name: "doc"}, validationOpts{method: create})
+	if !errors.As(err, &fe) || fe.Path() != "tenant_id" || fe.StructPath() != "Tenant.TenantID" {
+		t.Errorf("Expected FieldError with inline struct path, got %v", err)
+	}
+
+	// embedded structs with a name tag are still nested
+	got, err = v.validate(context.Background(), &Nested{Audit: Audit{CreatedBy: "admin"}}, validationOpts{method: create})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, ok := got["audit"].(map[string]interface{}); !ok {
+		t.Errorf("Expected nested audit map, got %v", got)
+	}
+
+	// embedded structs without a tag are skipped, like other fields
+	got, err = v.validate(context.Background(), &Untagged{Audit: Audit{CreatedBy: "admin"}, Name: "doc"}, validationOpts{method: create})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, map[string]interface{}{"name": "doc"}) {
+		t.Errorf("Untagged embedded data = %v, want only name", got)
+	}
+
+	errTests := []struct {
+		name string
+		data interface{}
+	}{
+		{"Conflicting field names", &Conflict{}},
+		{"Inline field with rules", &InvalidRules{}},
+		{"Inline field of non-struct type", &InvalidType{}},
+	}
+
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.validate(context.Background(), tt.data, validationOpts{method: create})
+			if err == nil {
+				t.Errorf("Expected error, got nil")
+			}
+		})
+	}
+}
+
+type testEvent interface {
+	EventName() string
+}
+
+type testCreatedEvent struct {
+	Name string `firevault:"name,required,transform:lowercase"`
+}
+
+func (e testCreatedEvent) EventName() string { return "created" }
+
+type testDeletedEvent struct {
+	Reason string    `firevault:"reason,omitempty"`
+	At     time.Time `firevault:"at"`
+}
+
+func (e *testDeletedEvent) EventName() string { return "deleted" }
+
+func TestPolymorphicTypes(t *testing.T) {
+	type Log struct {
+		Event   testEvent              `firevault:"event"`
+		History []testEvent            `firevault:"history,omitempty,dive"`
+		ByName  map[string]testEvent   `firevault:"by_name,omitempty"`
+		Extra   interface{}            `firevault:"extra,omitempty"`
+		Nested  map[string]interface{} `firevault:"nested,omitempty"`
+	}
+
+	type InvalidType struct {
+		Type string `firevault:"_type"`
+	}
+
+	v := newValidator()
+
+	if err := v.registerType("created", testCreatedEvent{}); err != nil {
+		t.Fatalf("Failed to register type: %v", err)
+	}
+
+	if err := v.registerType("deleted", &testDeletedEvent{}); err != nil {
+		t.Fatalf("Failed to register type: %v", err)
+	}
+
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	log := &Log{
+		Event:   testCreatedEvent{Name: "DOC"},
+		History: []testEvent{&testDeletedEvent{Reason: "spam", At: at}, nil},
+		ByName:  map[string]testEvent{"first": testCreatedEvent{Name: "doc"}},
+		Extra:   "extra",
+	}
+
+	got, err := v.validate(context.Background(), log, validationOpts{method: create})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"event": map[string]interface{}{"name": "doc", DiscriminatorField: "created"},
+		"history": []interface{}{
+			map[string]interface{}{"reason": "spam", "at": at, DiscriminatorField: "deleted"},
+			nil,
+		},
+		// without dive, interfaces nested in maps aren't validated
+		"by_name": map[string]testEvent{"first": testCreatedEvent{Name: "doc"}},
+		"extra":   "extra",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validated data = %v, want %v", got, want)
+	}
+
+	// concrete type's rules are applied
+	_, err = v.validate(context.Background(), &Log{Event: testCreatedEvent{}}, validationOpts{method: create})
+
+	var fe FieldError
+	if !errors.As(err, &fe) || fe.Path() != "event.name" || fe.Rule() != "required" {
+		t.Errorf("Expected required FieldError at event.name, got %v", err)
+	}
+
+	// interface documents (e.g. of CollectionRef[testEvent])
+	var event testEvent = &testDeletedEvent{At: at}
+
+	got, err = v.validate(context.Background(), &event, validationOpts{method: create})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want = map[string]interface{}{"at": at, DiscriminatorField: "deleted"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validated document = %v, want %v", got, want)
+	}
+
+	var unregistered interface{} = "event"
+
+	_, err = v.validate(context.Background(), &unregistered, validationOpts{method: create})
+	if err == nil {
+		t.Errorf("Expected error for unregistered document type, got nil")
+	}
+
+	// decode back into concrete types
+	var decodedLog Log
+
+	err = v.decodeValue(reflect.ValueOf(&decodedLog).Elem(), map[string]interface{}{
+		"event": map[string]interface{}{"name": "doc", DiscriminatorField: "created"},
+		"history": []interface{}{
+			map[string]interface{}{"reason": "spam", "at": at, DiscriminatorField: "deleted"},
+			nil,
+		},
+		"extra":  int64(1),
+		"nested": map[string]interface{}{"a": map[string]interface{}{"b": true}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	wantLog := Log{
+		Event:   testCreatedEvent{Name: "doc"},
+		History: []testEvent{&testDeletedEvent{Reason: "spam", At: at}, nil},
+		Extra:   int64(1),
+		Nested:  map[string]interface{}{"a": map[string]interface{}{"b": true}},
+	}
+	if !reflect.DeepEqual(decodedLog, wantLog) {
+		t.Errorf("Decoded data = %+v, want %+v", decodedLog, wantLog)
+	}
+
+	var decodedEvent testEvent
+
+	err = v.decodeValue(reflect.ValueOf(&decodedEvent).Elem(), map[string]interface{}{"at": at, DiscriminatorField: "deleted"})
+	if err != nil || !reflect.DeepEqual(decodedEvent, &testDeletedEvent{At: at}) {
+		t.Errorf("Decoded document = %v (%v), want %v", decodedEvent, err, &testDeletedEvent{At: at})
+	}
+
+	errTests := []struct {
+		name string
+		data map[string]interface{}
+	}{
+		{"Unknown type key", map[string]interface{}{DiscriminatorField: "updated"}},
+		{"Missing type key", map[string]interface{}{"name": "doc"}},
+		{"Mismatched field type", map[string]interface{}{"name": 1, DiscriminatorField: "created"}},
+	}
+
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decoded testEvent
+
+			err := v.decodeValue(reflect.ValueOf(&decoded).Elem(), tt.data)
+			if err == nil {
+				t.Errorf("Expected error, got nil")
+			}
+		})
+	}
+
+	type Plain struct {
+		Name string `firevault:"name"`
+	}
+
+	if !v.usesDecoder(reflect.TypeFor[Log]()) || v.usesDecoder(reflect.TypeFor[Plain]()) {
+		t.Errorf("Expected only Log to use the decoder")
+	}
+
+	if !v.usesRule(reflect.TypeFor[Log](), "required") || v.usesRule(reflect.TypeFor[Log](), "email") {
+		t.Errorf("Expected rules of registered types to be used by Log")
+	}
+
+	if err := v.registerType("invalid", InvalidType{}); err == nil {
+		t.Errorf("Expected error for type with discriminator field, got nil")
+	}
+}
+
+type testMoney struct {
+	cents int64
+}
+
+func (m testMoney) FirestoreValue() (interface{}, error) {
+	if m.cents < 0 {
+		return nil, errors.New("negative amount")
+	}
+
+	return m.cents, nil
+}
+
+func (m *testMoney) ScanFirestore(value interface{}) error {
+	cents, ok := value.(int64)
+	if !ok {
+		return fmt.Errorf("invalid amount %v", value)
+	}
+
+	m.cents = cents
+	return nil
+}
+
+func TestCustomTypes(t *testing.T) {
+	type Product struct {
+		Price   testMoney            `firevault:"price,required,min=100"`
+		Sale    *testMoney           `firevault:"sale,omitempty"`
+		History []testMoney          `firevault:"history,omitempty"`
+		Site    url.URL              `firevault:"site,omitempty"`
+		Links   map[string]*url.URL  `firevault:"links,omitempty"`
+		Prices  map[string]testMoney `firevault:"prices,omitempty,max=1"`
+	}
+
+	validator := NewValidator()
+
+	err := RegisterCustomType(
+		validator,
+		func(u url.URL) (interface{}, error) { return u.String(), nil },
+		func(value interface{}) (url.URL, error) {
+			u, err := url.Parse(fmt.Sprint(value))
+			if err != nil {
+				return url.URL{}, err
+			}
+
+			return *u, nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("Failed to register custom type: %v", err)
+	}
+
+	v := validator.validator
+	site, _ := url.Parse("https://example.com/a")
+
+	got, err := v.validate(context.Background(), &Product{
+		Price:   testMoney{150},
+		Sale:    &testMoney{120},
+		History: []testMoney{{100}, {200}},
+		Site:    *site,
+		Links:   map[string]*url.URL{"home": site, "none": nil},
+	}, validationOpts{method: create})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"price":   int64(150),
+		"sale":    int64(120),
+		"history": []interface{}{int64(100), int64(200)},
+		"site":    "https://example.com/a",
+		"links":   map[string]interface{}{"home": "https://example.com/a", "none": nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validated data = %#v, want %#v", got, want)
+	}
+
+	errTests := []struct {
+		name string
+		data *Product
+		rule string
+	}{
+		{"Built-in validation on marshalled value", &Product{Price: testMoney{50}}, "min"},
+		{"Required custom type", &Product{}, "required"},
+		{"Built-in validation on container", &Product{Price: testMoney{150}, Prices: map[string]testMoney{"a": {}, "b": {}}}, "max"},
+	}
+
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.validate(context.Background(), tt.data, validationOpts{method: create})
+
+			var fe FieldError
+			if !errors.As(err, &fe) || fe.Rule() != tt.rule {
+				t.Errorf("Expected %s FieldError, got %v", tt.rule, err)
+			}
+		})
+	}
+
+	_, err = v.validate(context.Background(), &Product{Price: testMoney{150}, Sale: &testMoney{-1}}, validationOpts{method: create})
+	if err == nil || err.Error() != "negative amount" {
+		t.Errorf("Expected marshalling error, got %v", err)
+	}
+
+	// decode back into custom types
+	if !v.usesDecoder(reflect.TypeFor[Product]()) {
+		t.Fatalf("Expected Product to use the decoder")
+	}
+
+	var decoded Product
+
+	err = v.decodeValue(reflect.ValueOf(&decoded).Elem(), want)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	wantProduct := Product{
+		Price:   testMoney{150},
+		Sale:    &testMoney{120},
+		History: []testMoney{{100}, {200}},
+		Site:    *site,
+		Links:   map[string]*url.URL{"home": site, "none": nil},
+	}
+	if !reflect.DeepEqual(decoded, wantProduct) {
+		t.Errorf("Decoded data = %+v, want %+v", decoded, wantProduct)
+	}
+
+	err = v.decodeValue(reflect.ValueOf(&decoded).Elem(), map[string]interface{}{"price": "150"})
+	if err == nil {
+		t.Errorf("Expected scanning error, got nil")
+	}
+
+	// query values
+	value, err := v.marshalQueryValue([]testMoney{{100}, {200}})
+	if err != nil || !reflect.DeepEqual(value, []interface{}{int64(100), int64(200)}) {
+		t.Errorf("Query value = %v (%v), want [100 200]", value, err)
+	}
+
+	value, err = v.marshalQueryValue(*site)
+	if err != nil || value != "https://example.com/a" {
+		t.Errorf("Query value = %v (%v), want https://example.com/a", value, err)
+	}
+
+	if err := RegisterCustomType[*url.URL](validator, nil, nil); err == nil {
+		t.Errorf("Expected error for empty functions, got nil")
+	}
+}
+
+func TestSpecialTypes(t *testing.T) {
+	type Blob []byte
+
+	type Place struct {
+		Owner     *firestore.DocumentRef   `firevault:"owner,required,collection=users"`
+		Friends   []*firestore.DocumentRef `firevault:"friends,omitempty,dive"`
+		Location  latlng.LatLng            `firevault:"location,geopoint"`
+		Latitude  float64                  `firevault:"latitude,omitempty,latitude"`
+		Longitude float64                  `firevault:"longitude,omitempty,longitude"`
+		Embedding firestore.Vector32       `firevault:"embedding,omitempty,dive,dimension=3"`
+		Photo     Blob                     `firevault:"photo,omitempty,dive,max_bytes=4"`
+	}
+
+	users := &firestore.CollectionRef{ID: "users", Path: "projects/p/databases/(default)/documents/users"}
+	owner := &firestore.DocumentRef{Parent: users, ID: "u1", Path: users.Path + "/u1"}
+	friend := &firestore.DocumentRef{Parent: users, ID: "u2", Path: users.Path + "/u2"}
+
+	v := newValidator()
+
+	got, err := v.validate(context.Background(), &Place{
+		Owner:     owner,
+		Friends:   []*firestore.DocumentRef{friend},
+		Location:  latlng.LatLng{Latitude: 51.5, Longitude: -0.12},
+		Embedding: firestore.Vector32{1, 2, 3},
+		Photo:     Blob("abc"),
+	}, validationOpts{method: create})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// references and geopoints are stored as pointers, while vectors and blobs aren't recursed into
+	if got["owner"] != owner || !reflect.DeepEqual(got["friends"], []interface{}{friend}) {
+		t.Errorf("Expected references to be stored as they are, got %v and %v", got["owner"], got["friends"])
+	}
+
+	if point, ok := got["location"].(*latlng.LatLng); !ok || point.GetLatitude() != 51.5 {
+		t.Errorf("Expected geopoint pointer, got %T", got["location"])
+	}
+
+	if !reflect.DeepEqual(got["embedding"], firestore.Vector32{1, 2, 3}) || !reflect.DeepEqual(got["photo"], []byte("abc")) {
+		t.Errorf("Expected vector and bytes, got %#v and %#v", got["embedding"], got["photo"])
+	}
+
+	errTests := []struct {
+		name  string
+		place *Place
+		rule  string
+	}{
+		{"Reference of another collection", &Place{Owner: &firestore.DocumentRef{Parent: &firestore.CollectionRef{ID: "teams"}}}, "collection"},
+		{"Invalid geopoint", &Place{Owner: owner, Location: latlng.LatLng{Latitude: 91}}, "geopoint"},
+		{"Invalid latitude", &Place{Owner: owner, Latitude: -90.5}, "latitude"},
+		{"Invalid longitude", &Place{Owner: owner, Longitude: 180.5}, "longitude"},
+		{"Invalid vector dimension", &Place{Owner: owner, Embedding: firestore.Vector32{1, 2}}, "dimension"},
+		{"Blob too large", &Place{Owner: owner, Photo: Blob("abcde")}, "max_bytes"},
+		{"Missing reference", &Place{}, "required"},
+	}
+
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.validate(context.Background(), tt.place, validationOpts{method: create})
+
+			var fe FieldError
+			if !errors.As(err, &fe) || fe.Rule() != tt.rule {
+				t.Errorf("Expected %s FieldError, got %v", tt.rule, err)
+			}
+		})
+	}
+
+	// decode values, as returned by Firestore
+	type Decoded struct {
+		Owner     firestore.DocumentRef `firevault:"owner"`
+		Location  *latlng.LatLng        `firevault:"location"`
+		Embedding firestore.Vector32    `firevault:"embedding"`
+		Photo     Blob                  `firevault:"photo"`
+	}
+
+	type Pointers struct {
+		Owner    *firestore.DocumentRef `firevault:"owner"`
+		Location *latlng.LatLng         `firevault:"location"`
+	}
+
+	if !v.usesDecoder(reflect.TypeFor[Decoded]()) || v.usesDecoder(reflect.TypeFor[Pointers]()) {
+		t.Errorf("Expected only reference and geopoint values to use the decoder")
+	}
+
+	var decoded Decoded
+
+	err = v.decodeValue(reflect.ValueOf(&decoded).Elem(), map[string]interface{}{
+		"owner":     owner,
+		"location":  &latlng.LatLng{Latitude: 1, Longitude: 2},
+		"embedding": firestore.Vector64{1, 2, 3},
+		"photo":     []byte("abc"),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if decoded.Owner.ID != "u1" || decoded.Location.GetLongitude() != 2 ||
+		!reflect.DeepEqual(decoded.Embedding, firestore.Vector32{1, 2, 3}) || string(decoded.Photo) != "abc" {
+		t.Errorf("Unexpected decoded data: %+v", decoded)
+	}
+
+	// query values
+	value, err := v.marshalQueryValue(*owner)
+	if err != nil || value.(*firestore.DocumentRef).ID != "u1" {
+		t.Errorf("Expected reference pointer as query value, got %T (%v)", value, err)
+	}
+}
+
+func TestDecoderFieldNames(t *testing.T) {
+	type Audit struct {
+		CreatedBy string `firevault:"created_by"`
+	}
+
+	type Profile struct {
+		FullName string `firevault:"full_name" firestore:"fullName"`
+		Age      int    `firevault:"age"`
+	}
+
+	type Base struct {
+		Owner string `firevault:"owner"`
+	}
+
+	type Matching struct {
+		Base  `firevault:",inline"`
+		Name  string `firevault:"name"`
+		Email string `firevault:"email" firestore:"email"`
+	}
+
+	type Inlined struct {
+		Audit Audit  `firevault:",inline"`
+		Name  string `firevault:"name"`
+	}
+
+	v := newValidator()
+
+	// types whose fields Firestore would decode using other names must use the decoder
+	tests := []struct {
+		name string
+		typ  reflect.Type
+		want bool
+	}{
+		{"Differing tags", reflect.TypeFor[Profile](), true},
+		{"Non-embedded inline struct", reflect.TypeFor[Inlined](), true},
+		{"Matching names", reflect.TypeFor[Matching](), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := v.usesDecoder(tt.typ); got != tt.want {
+				t.Errorf("validator.usesDecoder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var decoded Profile
+
+	// data is stored using Firevault names
+	err := v.decodeValue(reflect.ValueOf(&decoded).Elem(), map[string]interface{}{
+		"full_name": "Jane Doe",
+		"fullName":  "ignored",
+		"age":       int64(30),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if decoded.FullName != "Jane Doe" || decoded.Age != 30 {
+		t.Errorf("Unexpected decoded data: %+v", decoded)
+	}
+
+	var inlined Inlined
+
+	err = v.decodeValue(reflect.ValueOf(&inlined).Elem(), map[string]interface{}{"created_by": "admin", "name": "doc"})
+	if err != nil || inlined.Audit.CreatedBy != "admin" || inlined.Name != "doc" {
+		t.Errorf("Unexpected decoded data: %+v (%v)", inlined, err)
+	}
+}
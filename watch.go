@@ -0,0 +1,397 @@
+package firevault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ChangeKind describes the way a document changed
+// between two snapshots.
+type ChangeKind int
+
+const (
+	// The document started matching (e.g. it was
+	// created).
+	DocumentAdded ChangeKind = iota
+	// The document's data changed, and it still
+	// matches.
+	DocumentModified
+	// The document stopped matching (e.g. it was
+	// deleted).
+	DocumentRemoved
+)
+
+// Change describes the change of a single document
+// between two snapshots.
+type Change[T interface{}] struct {
+	Kind ChangeKind
+	// The document's current data (or its last known
+	// data, if it was removed).
+	Document Document[T]
+	// The document's index before the change, or -1
+	// if it was added.
+	OldIndex int
+	// The document's index after the change, or -1
+	// if it was removed.
+	NewIndex int
+}
+
+// Snapshot holds the documents which match a watched
+// Query, as well as their changes since the previous
+// snapshot.
+type Snapshot[T interface{}] struct {
+	// All matching documents, in the Query's order.
+	Documents []Document[T]
+	// The changes since the previous snapshot, as
+	// reported by Firestore (removals, additions, then
+	// modifications). Applying them in order to the
+	// previous Documents gives the current ones. In the
+	// first snapshot, all documents are added.
+	Changes []Change[T]
+	// The time at which the documents were read.
+	ReadTime time.Time
+}
+
+// delays between attempts to restart a listener
+const (
+	minRetryDelay = 250 * time.Millisecond
+	maxRetryDelay = 30 * time.Second
+)
+
+// an error returned by a handler, which always stops a listener
+type handlerError struct {
+	err error
+}
+
+func (e handlerError) Error() string {
+	return e.err.Error()
+}
+
+// Watch listens to the Firestore documents which match
+// provided Query, calling handler with a new Snapshot
+// each time they change.
+//
+// The first Snapshot holds all matching documents (as
+// added), while each subsequent one holds the changes
+// since the previous one.
+//
+// Watch blocks until the context is cancelled (in which
+// case it returns nil), handler returns an error (which
+// is returned), or the listener fails. Listeners are
+// restarted after transient errors (e.g. the network
+// being unavailable), delivering any changes which
+// happened in the meantime. As these are found by
+// comparing the documents, their OldIndex refers to the
+// previous Snapshot and their NewIndex to the current
+// one.
+//
+// Watch can't be used with ID or FindNearest. To watch
+// a single document, use WatchDocument.
+func (c *CollectionRef[T]) Watch(ctx context.Context, query Query, handler func(Snapshot[T]) error) error {
+	if c == nil {
+		return errors.New("firevault: nil CollectionRef")
+	}
+
+	if len(query.ids) > 0 {
+		return errors.New("firevault: Watch cannot be used with ID, use WatchDocument instead")
+	}
+
+	if query.nearest != nil {
+		return errors.New("firevault: Watch cannot be used with FindNearest")
+	}
+
+	builtQuery, err := c.buildQuery(query)
+	if err != nil {
+		return err
+	}
+
+	w := &queryWatch[T]{
+		decode: func(docSnap *firestore.DocumentSnapshot) (Document[T], error) {
+			return c.snapshotDoc(docSnap, "")
+		},
+		key: c.docKey,
+	}
+
+	return listen(ctx, func() (func() error, func()) {
+		iter := builtQuery.Snapshots(ctx)
+		w.start()
+
+		next := func() error {
+			querySnap, err := iter.Next()
+			if err != nil {
+				return err
+			}
+
+			snapshot, ok, err := w.next(querySnap)
+			if err != nil || !ok {
+				return err
+			}
+
+			err = handler(snapshot)
+			if err != nil {
+				return handlerError{err}
+			}
+
+			return nil
+		}
+
+		return next, iter.Stop
+	})
+}
+
+// WatchDocument listens to a single Firestore document,
+// calling handler with a Change each time it's created,
+// modified or deleted.
+//
+// If the document exists when WatchDocument is called,
+// handler is first called with it (as added). Change
+// indexes are always 0 (or -1, for added and removed
+// documents).
+//
+// Like Watch, it blocks until the context is cancelled
+// (in which case it returns nil), handler returns an
+// error (which is returned), or the listener fails, and
+// restarts the listener after transient errors.
+func (c *CollectionRef[T]) WatchDocument(ctx context.Context, docID string, handler func(Change[T]) error) error {
+	if c == nil {
+		return errors.New("firevault: nil CollectionRef")
+	}
+
+	if docID == "" {
+		return errors.New("firevault: document ID cannot be empty")
+	}
+
+	ref, err := c.doc(docID)
+	if err != nil {
+		return err
+	}
+	if ref == nil {
+		return errors.New("firevault: invalid document ID " + docID)
+	}
+
+	var previous []Document[T]
+
+	return listen(ctx, func() (func() error, func()) {
+		iter := ref.Snapshots(ctx)
+
+		next := func() error {
+			docSnap, err := iter.Next()
+			if err != nil {
+				return err
+			}
+
+			var docs []Document[T]
+
+			if docSnap.Exists() {
+				doc, err := c.snapshotDoc(docSnap, "")
+				if err != nil {
+					return err
+				}
+
+				docs = append(docs, doc)
+			}
+
+			changes := diffDocuments(previous, docs, c.docKey)
+			previous = docs
+
+			for _, change := range changes {
+				if err := handler(change); err != nil {
+					return handlerError{err}
+				}
+			}
+
+			return nil
+		}
+
+		return next, iter.Stop
+	})
+}
+
+// run a listener until the context is cancelled or it fails, restarting it after transient errors
+func listen(ctx context.Context, start func() (next func() error, stop func())) error {
+	delay := minRetryDelay
+
+	for {
+		next, stop := start()
+
+		err := func() error {
+			defer stop()
+
+			for {
+				if err := next(); err != nil {
+					return err
+				}
+
+				// the listener recovered
+				delay = minRetryDelay
+			}
+		}()
+
+		var hErr handlerError
+		if errors.As(err, &hErr) {
+			return hErr.err
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if !isTransient(err) {
+			return wrapFirestoreError(err)
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// check if a listener's error is likely to be resolved by restarting it
+func isTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+
+	return false
+}
+
+// tracks the documents matching a watched query, across listener restarts
+type queryWatch[T interface{}] struct {
+	docs      []Document[T]
+	delivered bool
+	restarted bool
+	decode    func(*firestore.DocumentSnapshot) (Document[T], error)
+	key       func(Document[T]) string
+}
+
+// prepare for a new listener, whose first snapshot holds all documents as added
+func (w *queryWatch[T]) start() {
+	w.restarted = w.delivered
+}
+
+// apply the changes of a query snapshot, reporting whether the Snapshot should be delivered
+func (w *queryWatch[T]) next(querySnap *firestore.QuerySnapshot) (Snapshot[T], bool, error) {
+	base := w.docs
+	if w.restarted {
+		base = nil
+	}
+
+	docs, changes, err := applyChanges(base, querySnap.Changes, w.decode)
+	if err != nil {
+		return Snapshot[T]{}, false, err
+	}
+
+	// restarted listeners resend unchanged documents, so only the differences are delivered
+	if w.restarted {
+		w.restarted = false
+		changes = diffDocuments(w.docs, docs, w.key)
+
+		if len(changes) == 0 {
+			w.docs = docs
+			return Snapshot[T]{}, false, nil
+		}
+	}
+
+	w.docs = docs
+	w.delivered = true
+
+	return Snapshot[T]{docs, changes, querySnap.ReadTime}, true, nil
+}
+
+// apply Firestore's changes to a copy of the documents, only decoding added and modified ones
+func applyChanges[T interface{}](
+	previous []Document[T],
+	docChanges []firestore.DocumentChange,
+	decode func(*firestore.DocumentSnapshot) (Document[T], error),
+) ([]Document[T], []Change[T], error) {
+	docs := slices.Clone(previous)
+	changes := make([]Change[T], 0, len(docChanges))
+
+	for _, docChange := range docChanges {
+		if docChange.Kind != firestore.DocumentAdded &&
+			(docChange.OldIndex < 0 || docChange.OldIndex >= len(docs)) {
+			return nil, nil, fmt.Errorf("firevault: invalid snapshot change index %d", docChange.OldIndex)
+		}
+
+		if docChange.Kind == firestore.DocumentRemoved {
+			// removed documents keep their last known data
+			changes = append(changes, Change[T]{DocumentRemoved, docs[docChange.OldIndex], docChange.OldIndex, -1})
+			docs = slices.Delete(docs, docChange.OldIndex, docChange.OldIndex+1)
+			continue
+		}
+
+		doc, err := decode(docChange.Doc)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		kind := DocumentAdded
+		if docChange.Kind == firestore.DocumentModified {
+			kind = DocumentModified
+			docs = slices.Delete(docs, docChange.OldIndex, docChange.OldIndex+1)
+		}
+
+		if docChange.NewIndex < 0 || docChange.NewIndex > len(docs) {
+			return nil, nil, fmt.Errorf("firevault: invalid snapshot change index %d", docChange.NewIndex)
+		}
+
+		docs = slices.Insert(docs, docChange.NewIndex, doc)
+		changes = append(changes, Change[T]{kind, doc, docChange.OldIndex, docChange.NewIndex})
+	}
+
+	return docs, changes, nil
+}
+
+// get the changes between two snapshots of documents
+func diffDocuments[T interface{}](
+	previous []Document[T],
+	current []Document[T],
+	key func(Document[T]) string,
+) []Change[T] {
+	var changes []Change[T]
+
+	previousIndexes := make(map[string]int, len(previous))
+	for i, doc := range previous {
+		previousIndexes[key(doc)] = i
+	}
+
+	currentKeys := make(map[string]bool, len(current))
+	for _, doc := range current {
+		currentKeys[key(doc)] = true
+	}
+
+	for i, doc := range previous {
+		if !currentKeys[key(doc)] {
+			changes = append(changes, Change[T]{DocumentRemoved, doc, i, -1})
+		}
+	}
+
+	for i, doc := range current {
+		oldIndex, ok := previousIndexes[key(doc)]
+		if !ok {
+			changes = append(changes, Change[T]{DocumentAdded, doc, -1, i})
+			continue
+		}
+
+		if !doc.Metadata.UpdateTime.Equal(previous[oldIndex].Metadata.UpdateTime) {
+			changes = append(changes, Change[T]{DocumentModified, doc, oldIndex, i})
+		}
+	}
+
+	return changes
+}
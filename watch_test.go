@@ -0,0 +1,194 @@
+package firevault
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWatch(t *testing.T) {
+	newDoc := func(id string, updated int64) Document[map[string]interface{}] {
+		return Document[map[string]interface{}]{ID: id, Metadata: metadata{UpdateTime: time.Unix(updated, 0)}}
+	}
+
+	key := func(doc Document[map[string]interface{}]) string { return doc.ID }
+
+	previous := []Document[map[string]interface{}]{newDoc("a", 1), newDoc("b", 1), newDoc("c", 1)}
+	current := []Document[map[string]interface{}]{newDoc("c", 1), newDoc("a", 2), newDoc("d", 1)}
+
+	want := []Change[map[string]interface{}]{
+		{DocumentRemoved, newDoc("b", 1), 1, -1},
+		{DocumentModified, newDoc("a", 2), 0, 1},
+		{DocumentAdded, newDoc("d", 1), -1, 2},
+	}
+
+	if got := diffDocuments(previous, current, key); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffDocuments() = %+v, want %+v", got, want)
+	}
+
+	if got := diffDocuments(current, current, key); len(got) != 0 {
+		t.Errorf("Expected no changes between identical snapshots, got %+v", got)
+	}
+
+	// changes reported by Firestore are applied in order, keeping their indexes
+	snap := func(id string, updated int64) *firestore.DocumentSnapshot {
+		return &firestore.DocumentSnapshot{Ref: &firestore.DocumentRef{ID: id}, UpdateTime: time.Unix(updated, 0)}
+	}
+
+	decodes := 0
+	w := &queryWatch[map[string]interface{}]{
+		decode: func(docSnap *firestore.DocumentSnapshot) (Document[map[string]interface{}], error) {
+			decodes++
+			return newDoc(docSnap.Ref.ID, docSnap.UpdateTime.Unix()), nil
+		},
+		key: key,
+	}
+
+	steps := []struct {
+		name        string
+		restart     bool
+		changes     []firestore.DocumentChange
+		wantDocs    []string
+		wantChanges []Change[map[string]interface{}]
+		wantDecodes int
+		wantSkipped bool
+	}{
+		{
+			name: "Initial snapshot",
+			changes: []firestore.DocumentChange{
+				{Kind: firestore.DocumentAdded, Doc: snap("a", 1), OldIndex: -1, NewIndex: 0},
+				{Kind: firestore.DocumentAdded, Doc: snap("b", 1), OldIndex: -1, NewIndex: 1},
+				{Kind: firestore.DocumentAdded, Doc: snap("c", 1), OldIndex: -1, NewIndex: 2},
+			},
+			wantDocs: []string{"a", "b", "c"},
+			wantChanges: []Change[map[string]interface{}]{
+				{DocumentAdded, newDoc("a", 1), -1, 0},
+				{DocumentAdded, newDoc("b", 1), -1, 1},
+				{DocumentAdded, newDoc("c", 1), -1, 2},
+			},
+			wantDecodes: 3,
+		},
+		{
+			name: "Removal and move",
+			changes: []firestore.DocumentChange{
+				{Kind: firestore.DocumentRemoved, Doc: snap("a", 1), OldIndex: 0, NewIndex: -1},
+				{Kind: firestore.DocumentModified, Doc: snap("c", 2), OldIndex: 1, NewIndex: 0},
+			},
+			wantDocs: []string{"c", "b"},
+			wantChanges: []Change[map[string]interface{}]{
+				{DocumentRemoved, newDoc("a", 1), 0, -1},
+				{DocumentModified, newDoc("c", 2), 1, 0},
+			},
+			wantDecodes: 1,
+		},
+		{
+			name:    "Restart resending unchanged documents",
+			restart: true,
+			changes: []firestore.DocumentChange{
+				{Kind: firestore.DocumentAdded, Doc: snap("c", 2), OldIndex: -1, NewIndex: 0},
+				{Kind: firestore.DocumentAdded, Doc: snap("b", 1), OldIndex: -1, NewIndex: 1},
+			},
+			wantDocs:    []string{"c", "b"},
+			wantDecodes: 2,
+			wantSkipped: true,
+		},
+		{
+			name:    "Restart after changes",
+			restart: true,
+			changes: []firestore.DocumentChange{
+				{Kind: firestore.DocumentAdded, Doc: snap("b", 2), OldIndex: -1, NewIndex: 0},
+				{Kind: firestore.DocumentAdded, Doc: snap("d", 1), OldIndex: -1, NewIndex: 1},
+			},
+			wantDocs: []string{"b", "d"},
+			wantChanges: []Change[map[string]interface{}]{
+				{DocumentRemoved, newDoc("c", 2), 0, -1},
+				{DocumentModified, newDoc("b", 2), 1, 0},
+				{DocumentAdded, newDoc("d", 1), -1, 1},
+			},
+			wantDecodes: 2,
+		},
+	}
+
+	for _, step := range steps {
+		decodes = 0
+		if step.restart {
+			w.start()
+		}
+
+		snapshot, ok, err := w.next(&firestore.QuerySnapshot{Changes: step.changes})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", step.name, err)
+		}
+
+		var ids []string
+		for _, doc := range w.docs {
+			ids = append(ids, doc.ID)
+		}
+
+		if !reflect.DeepEqual(ids, step.wantDocs) || decodes != step.wantDecodes || ok == step.wantSkipped {
+			t.Errorf("%s: got documents %v after %d decodes (delivered %v)", step.name, ids, decodes, ok)
+		}
+
+		if ok && !reflect.DeepEqual(snapshot.Changes, step.wantChanges) {
+			t.Errorf("%s: changes = %+v, want %+v", step.name, snapshot.Changes, step.wantChanges)
+		}
+	}
+
+	invalid := []firestore.DocumentChange{{Kind: firestore.DocumentRemoved, Doc: snap("x", 1), OldIndex: 5, NewIndex: -1}}
+	if _, _, err := w.next(&firestore.QuerySnapshot{Changes: invalid}); err == nil {
+		t.Errorf("Expected error for change with an invalid index")
+	}
+
+	// listeners are restarted after transient errors only
+	starts := 0
+	errs := []error{status.Error(codes.Unavailable, "unavailable"), status.Error(codes.PermissionDenied, "denied")}
+
+	err := listen(context.Background(), func() (func() error, func()) {
+		err := errs[starts]
+		starts++
+
+		return func() error { return err }, func() {}
+	})
+	if status.Code(err) != codes.PermissionDenied || starts != 2 {
+		t.Errorf("Expected PermissionDenied after 2 starts, got %v after %d", err, starts)
+	}
+
+	// handler errors are returned as they are, even if they look transient
+	handlerErr := status.Error(codes.Unavailable, "handler")
+	err = listen(context.Background(), func() (func() error, func()) {
+		return func() error { return handlerError{handlerErr} }, func() {}
+	})
+	if !errors.Is(err, handlerErr) {
+		t.Errorf("Expected handler error, got %v", err)
+	}
+
+	// cancelling the context stops listeners cleanly
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := false
+
+	err = listen(ctx, func() (func() error, func()) {
+		return func() error {
+			cancel()
+			return status.Error(codes.Canceled, "cancelled")
+		}, func() { stopped = true }
+	})
+	if err != nil || !stopped {
+		t.Errorf("Expected nil error and stopped listener after cancellation, got %v", err)
+	}
+
+	collection := &CollectionRef[map[string]interface{}]{
+		connection: &Connection{Validator: NewValidator()},
+		path:       "users",
+	}
+
+	handler := func(Snapshot[map[string]interface{}]) error { return nil }
+	if err := collection.Watch(context.Background(), NewQuery().ID("a"), handler); err == nil {
+		t.Errorf("Expected error when watching an ID query")
+	}
+}